@@ -0,0 +1,179 @@
+package lgtv
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ack is a decoded acknowledgement frame sent back by an LG TV set, e.g.
+// "a 01 OK01x": [Cmd2][ ][Set ID][ ][OK|NG][Data][x]
+type Ack struct {
+	Cmd2  string `json:"cmd2"`
+	ID    int    `json:"id"`
+	OK    bool   `json:"ok"`
+	Data  string `json:"data,omitempty"`
+	Value int    `json:"value"`
+	Key   string `json:"key,omitempty"`
+}
+
+// AckParser decodes acknowledgement frames and resolves them to Cmd keys
+// using a RespMap built by GetRespMap.
+type AckParser struct {
+	// Cmd1 is the first command letter of the request being acknowledged,
+	// which the TV doesn't echo. If empty, a key is only resolved when a
+	// single Cmd entry matches.
+	Cmd1 string
+	Resp RespMap
+}
+
+// FrameError reports a malformed acknowledgement frame.
+type FrameError struct {
+	Frame  string
+	Reason string
+}
+
+var (
+	// ackRx finds a well formed acknowledgement at the end of a chunk
+	ackRx = regexp.MustCompile(`[a-z] [0-9A-Fa-f]{1,2} (?:OK|NG)[0-9A-Fa-f]*x$`)
+
+	// cmd1s are the first command letters used by the LG RS-232C protocol
+	cmd1s = []string{"k", "j", "m", "d", "x", ""}
+)
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("malformed frame %q: %s", e.Frame, e.Reason)
+}
+
+// Unwrap makes every FrameError match ErrBadFrame.
+func (e *FrameError) Unwrap() error {
+	return ErrBadFrame
+}
+
+// ParseAck decodes a single acknowledgement frame. Leading white space is
+// ignored, anything else that doesn't fit the frame layout is a *FrameError.
+func ParseAck(frame []byte) (Ack, error) {
+	var a Ack
+
+	bad := func(reason string) (Ack, error) {
+		return a, &FrameError{Frame: string(frame), Reason: reason}
+	}
+
+	f := bytes.TrimLeft(frame, " \r\n")
+	switch {
+	case len(f) == 0:
+		return bad("empty frame")
+	case f[len(f)-1] != 'x':
+		return bad("missing 'x' terminator")
+	}
+
+	fields := bytes.Fields(f[:len(f)-1])
+	if len(fields) != 3 {
+		return bad(fmt.Sprintf("want 3 fields, got %d", len(fields)))
+	}
+
+	if c := fields[0]; len(c) != 1 || c[0] < 'a' || c[0] > 'z' {
+		return bad("bad command letter")
+	}
+	a.Cmd2 = string(fields[0])
+
+	id, ok := parseSetID(string(fields[1]))
+	if !ok {
+		return bad("bad set ID")
+	}
+	a.ID = id
+
+	switch st := fields[2]; {
+	case bytes.HasPrefix(st, []byte("OK")):
+		a.OK = true
+	case bytes.HasPrefix(st, []byte("NG")):
+	default:
+		return bad("missing OK/NG status")
+	}
+
+	if a.Data = string(fields[2][2:]); a.Data != "" {
+		v, err := strconv.ParseUint(a.Data, 16, 32)
+		if err != nil {
+			return bad("data is not hexadecimal")
+		}
+		a.Value = int(v)
+	}
+
+	return a, nil
+}
+
+// frame encodes a as the acknowledgement frame a TV set sends.
+func (a Ack) frame() string {
+	code := "NG"
+	if a.OK {
+		code = "OK"
+	}
+	return fmt.Sprintf("%s %s %s%sx", a.Cmd2, setID(a.ID), code, a.Data)
+}
+
+// ScanAcks returns every acknowledgement found in buf, skipping noise
+// between frames, and the trailing bytes of a partial frame still to come.
+func ScanAcks(buf []byte) ([]Ack, []byte) {
+	var acks []Ack
+
+	for {
+		i := bytes.IndexByte(buf, 'x')
+		if i < 0 {
+			return acks, buf
+		}
+
+		if m := ackRx.Find(buf[:i+1]); m != nil {
+			if a, err := ParseAck(m); err == nil {
+				acks = append(acks, a)
+			}
+		}
+		buf = buf[i+1:]
+	}
+}
+
+// Parse decodes a single acknowledgement frame and resolves its Cmd key.
+func (p AckParser) Parse(frame []byte) (Ack, error) {
+	a, err := ParseAck(frame)
+	if err != nil {
+		return a, err
+	}
+	a.Key = p.key(a)
+	return a, nil
+}
+
+// Scan works like ScanAcks and resolves the Cmd key of each frame found.
+func (p AckParser) Scan(buf []byte) ([]Ack, []byte) {
+	acks, rest := ScanAcks(buf)
+	for i := range acks {
+		acks[i].Key = p.key(acks[i])
+	}
+	return acks, rest
+}
+
+func (p AckParser) key(a Ack) string {
+	code := "NG"
+	if a.OK {
+		code = "OK"
+	}
+
+	find := func(cmd1 string) string {
+		return p.Resp[a.ID][fmt.Sprintf("%s %s %s %s %s x", cmd1, a.Cmd2, code, setID(a.ID), strings.ToUpper(a.Data))]
+	}
+
+	if p.Cmd1 != "" {
+		return find(p.Cmd1)
+	}
+
+	var key string
+	for _, cmd1 := range cmd1s {
+		if k := find(cmd1); k != "" {
+			if key != "" {
+				return ""
+			}
+			key = k
+		}
+	}
+	return key
+}
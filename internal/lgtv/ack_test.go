@@ -0,0 +1,124 @@
+package lgtv
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseAck(t *testing.T) {
+	Convey("Testing ParseAck()", t, func() {
+		tests := []struct {
+			name   string
+			frame  string
+			want   Ack
+			reason string
+		}{
+			{name: "OK", frame: "a 01 OK01x", want: Ack{Cmd2: "a", ID: 1, OK: true, Data: "01", Value: 1}},
+			{name: "NG", frame: "f 02 NG00x", want: Ack{Cmd2: "f", ID: 2, Data: "00"}},
+			{name: "Hexadecimal data", frame: "l 01 OK1A2Bx", want: Ack{Cmd2: "l", ID: 1, OK: true, Data: "1A2B", Value: 0x1a2b}},
+			{name: "No data", frame: "a 01 NGx", want: Ack{Cmd2: "a", ID: 1}},
+			{name: "Leading CRLF", frame: "\r\na 03 OK00x", want: Ack{Cmd2: "a", ID: 3, OK: true, Data: "00"}},
+			{name: "Empty", frame: "", reason: "empty frame"},
+			{name: "Partial", frame: "a 01 OK0", reason: "missing 'x' terminator"},
+			{name: "Too few fields", frame: "a OK01x", reason: "want 3 fields, got 2"},
+			{name: "Bad command", frame: "A 01 OK01x", reason: "bad command letter"},
+			{name: "Hexadecimal set ID", frame: "a 0a OK01x", want: Ack{Cmd2: "a", ID: 10, OK: true, Data: "01", Value: 1}},
+			{name: "Bad set ID", frame: "a 1G OK01x", reason: "bad set ID"},
+			{name: "Set ID past 99", frame: "a 64 OK01x", reason: "bad set ID"},
+			{name: "Bad status", frame: "a 01 XX01x", reason: "missing OK/NG status"},
+			{name: "Bad data", frame: "a 01 OKZZx", reason: "data is not hexadecimal"},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				got, err := ParseAck([]byte(tt.frame))
+				if tt.reason != "" {
+					So(err, ShouldResemble, &FrameError{Frame: tt.frame, Reason: tt.reason})
+					return
+				}
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+			})
+		}
+	})
+}
+
+func TestScanAcks(t *testing.T) {
+	Convey("Testing ScanAcks()", t, func() {
+		tests := []struct {
+			name string
+			buf  string
+			want []Ack
+			rest string
+		}{
+			{
+				name: "Several frames",
+				buf:  "a 01 OK01xa 02 NG00x",
+				want: []Ack{{Cmd2: "a", ID: 1, OK: true, Data: "01", Value: 1}, {Cmd2: "a", ID: 2, Data: "00"}},
+			},
+			{
+				name: "Noise between frames",
+				buf:  "\x00\xffgarbage a 01 OK01x\r\nzzzx a 02 OK10x",
+				want: []Ack{{Cmd2: "a", ID: 1, OK: true, Data: "01", Value: 1}, {Cmd2: "a", ID: 2, OK: true, Data: "10", Value: 16}},
+			},
+			{
+				name: "Partial trailing frame",
+				buf:  "a 01 OK01xf 01 OK",
+				want: []Ack{{Cmd2: "a", ID: 1, OK: true, Data: "01", Value: 1}},
+				rest: "f 01 OK",
+			},
+			{
+				name: "Nothing yet",
+				buf:  "a 0",
+				rest: "a 0",
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				got, rest := ScanAcks([]byte(tt.buf))
+				So(got, ShouldResemble, tt.want)
+				So(string(rest), ShouldEqual, tt.rest)
+			})
+		}
+	})
+}
+
+func TestAckParser(t *testing.T) {
+	Convey("Testing AckParser", t, func() {
+		tv := TVCmds{
+			"Aspect16:9": {Cmd1: "k", Cmd2: "c", Data: "02"},
+			"Num0":       {Cmd1: "m", Cmd2: "c", Data: "02"},
+			"PowerOn":    {Cmd1: "k", Cmd2: "a", Data: "01"},
+			"VolSet":     {Cmd1: "k", Cmd2: "f", Max: 64},
+		}
+		tests := []struct {
+			name  string
+			cmd1  string
+			frame string
+			want  string
+		}{
+			{name: "Unique match", frame: "a 01 OK01x", want: "PowerOn"},
+			{name: "Ranged command", frame: "f 0a OK1Ex", want: "VolSet"},
+			{name: "Ranged command, lower case", frame: "f 02 OK0ax", want: "VolSet"},
+			{name: "Beyond the range", frame: "f 02 OK65x", want: ""},
+			{name: "Ambiguous without Cmd1", frame: "c 01 OK02x", want: ""},
+			{name: "Disambiguated by Cmd1", cmd1: "m", frame: "c 01 OK02x", want: "Num0"},
+			{name: "No match", frame: "a 01 OK07x", want: ""},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				p := AckParser{Cmd1: tt.cmd1, Resp: tv.RespMapFor(MaxSetID)}
+				got, err := p.Parse([]byte(tt.frame))
+				So(err, ShouldBeNil)
+				So(got.Key, ShouldEqual, tt.want)
+
+				acks, _ := p.Scan([]byte("noise" + tt.frame))
+				So(len(acks), ShouldEqual, 1)
+				So(acks[0].Key, ShouldEqual, tt.want)
+			})
+		}
+	})
+}
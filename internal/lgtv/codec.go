@@ -0,0 +1,95 @@
+package lgtv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encode builds the frame that sends command l to TV set id, e.g.
+// "ka 01 01\r". Ranged commands send value, which must be within the
+// command's bounds; the others send their own Data and ignore value.
+func Encode(l LGCmd, id, value int) ([]byte, error) {
+	return l.frame(l.Cmd1+l.Cmd2, id, value)
+}
+
+// Decode returns the acknowledgement to command l found in resp from TV set
+// id. An NG acknowledgement isn't an error; Ack.OK reports it.
+func Decode(l LGCmd, id int, resp []byte) (Ack, error) {
+	a, ok := findAck(resp, l.Cmd2, id)
+	if !ok {
+		return a, &FrameError{Frame: string(resp), Reason: "no acknowledgement for " + l.Cmd1 + l.Cmd2}
+	}
+	return a, nil
+}
+
+// frame encodes command l for TV set id, calling it name in errors.
+func (l LGCmd) frame(name string, id, value int) ([]byte, error) {
+	if l.Cmd1 == "" || l.Cmd2 == "" {
+		return nil, fmt.Errorf("%q has no serial command", name)
+	}
+	if id < BroadcastID || id > MaxSetID {
+		return nil, fmt.Errorf("TV set ID %d out of range %d..%d", id, BroadcastID, MaxSetID)
+	}
+
+	data := l.Data
+	if l.Max > 0 {
+		var err error
+		if data, err = l.encode(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return frame(l.Cmd1, l.Cmd2, setID(id), data), nil
+}
+
+// parseFrame splits a command frame such as "ka 01 01" into its fields.
+func parseFrame(fr string) (cmd1, cmd2 string, id int, data string, ok bool) {
+	f := strings.Fields(fr)
+	if len(f) != 3 || len(f[0]) != 2 {
+		return "", "", 0, "", false
+	}
+	f = []string{f[0][:1], f[0][1:], f[1], f[2]}
+
+	if id, ok = parseSetID(f[2]); !ok {
+		return "", "", 0, "", false
+	}
+	return f[0], f[1], id, strings.ToUpper(f[3]), true
+}
+
+// parseKey resolves a command key, either a Cmd name such as "PowerOn" or a
+// TVCmpMap key that appends the frame data to it, such as "PowerOn01" or
+// "VolSet32". Ranged keys carry their data in hexadecimal as it's sent, so
+// "VolSet32" sets the volume to 50.
+func parseKey(key string) (string, LGCmd, int, error) {
+	if l, ok := Cmd[key]; ok {
+		if l.Max > 0 {
+			return "", l, 0, fmt.Errorf("%q needs a value", key)
+		}
+		return key, l, 0, nil
+	}
+
+	// Longest name first, so "Abnormal1" never claims "Abnormal10".
+	names := make([]string, 0, len(Cmd))
+	for k := range Cmd {
+		if strings.HasPrefix(key, k) && len(key) > len(k) {
+			names = append(names, k)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	for _, name := range names {
+		l, data := Cmd[name], key[len(name):]
+		switch {
+		case l.Max == 0 && strings.EqualFold(data, l.Data):
+			return name, l, 0, nil
+		case l.Max > 0:
+			if v, err := strconv.ParseUint(data, 16, 16); err == nil {
+				return name, l, int(v), nil
+			}
+		}
+	}
+
+	return "", LGCmd{}, 0, fmt.Errorf("unknown command %q", key)
+}
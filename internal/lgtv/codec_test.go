@@ -0,0 +1,205 @@
+package lgtv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEncode(t *testing.T) {
+	Convey("Testing Encode()", t, func() {
+		tests := []struct {
+			name   string
+			cmd    string
+			id     int
+			value  int
+			want   string
+			errMsg string
+		}{
+			{name: "Fixed", cmd: "PowerOn", id: 1, want: "ka 01 01\r"},
+			{name: "Fixed ignores value", cmd: "MuteOn", id: 2, value: 7, want: "ke 02 00\r"},
+			{name: "Read", cmd: "VolLvl", id: 99, want: "kf 63 FF\r"},
+			{name: "Hexadecimal set ID", cmd: "PowerOn", id: 10, want: "ka 0a 01\r"},
+			{name: "Broadcast", cmd: "PowerOff", id: BroadcastID, want: "ka 00 00\r"},
+			{name: "Ranged", cmd: "VolSet", id: 1, value: 30, want: "kf 01 1E\r"},
+			{name: "Ranged hex bound", cmd: "TileID", id: 1, value: 16, want: "di 01 10\r"},
+			{name: "Out of range", cmd: "VolSet", id: 1, value: 101, errMsg: "kf: 101 is out of range 0..100"},
+			{name: "Set ID too high", cmd: "PowerOn", id: 100, errMsg: "TV set ID 100 out of range 0..99"},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				got, err := Encode(Cmd[tt.cmd], tt.id, tt.value)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(string(got), ShouldEqual, tt.want)
+			})
+		}
+	})
+}
+
+func TestDecode(t *testing.T) {
+	Convey("Testing Decode()", t, func() {
+		tests := []struct {
+			name   string
+			cmd    string
+			id     int
+			resp   string
+			want   Ack
+			errMsg string
+		}{
+			{name: "OK", cmd: "PowerOn", id: 1, resp: "a 01 OK01x", want: Ack{Cmd2: "a", ID: 1, OK: true, Data: "01", Value: 1}},
+			{name: "NG", cmd: "VolSet", id: 3, resp: "f 03 NG65x", want: Ack{Cmd2: "f", ID: 3, Data: "65", Value: 0x65}},
+			{name: "Echo and noise", cmd: "VolLvl", id: 1, resp: "kf 01 FF\rf 01 OK1Ex", want: Ack{Cmd2: "f", ID: 1, OK: true, Data: "1E", Value: 30}},
+			{name: "Other set", cmd: "PowerOn", id: 1, resp: "a 02 OK01x", errMsg: `malformed frame "a 02 OK01x": no acknowledgement for ka`},
+			{name: "Other command", cmd: "PowerOn", id: 1, resp: "e 01 OK01x", errMsg: `malformed frame "e 01 OK01x": no acknowledgement for ka`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				got, err := Decode(Cmd[tt.cmd], tt.id, []byte(tt.resp))
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+			})
+		}
+	})
+}
+
+func TestParseKey(t *testing.T) {
+	Convey("Testing parseKey()", t, func() {
+		tests := []struct {
+			key    string
+			name   string
+			value  int
+			errMsg string
+		}{
+			{key: "PowerOn", name: "PowerOn"},
+			{key: "PowerOn01", name: "PowerOn"},
+			{key: "PowerOff00", name: "PowerOff"},
+			{key: "VolSet32", name: "VolSet", value: 0x32},
+			{key: "TileID0A", name: "TileID", value: 10},
+			{key: "VolSet", errMsg: `"VolSet" needs a value`},
+			{key: "PowerOn02", errMsg: `unknown command "PowerOn02"`},
+			{key: "Bogus", errMsg: `unknown command "Bogus"`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.key, func() {
+				name, _, value, err := parseKey(tt.key)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(name, ShouldEqual, tt.name)
+				So(value, ShouldEqual, tt.value)
+			})
+		}
+	})
+}
+
+func TestXmitEncoded(t *testing.T) {
+	Convey("Testing Xmit() without a TVCmpMap", t, func() {
+		tests := []struct {
+			name   string
+			cmd    string
+			id     int
+			xmit   string
+			reply  string
+			want   bool
+			errMsg string
+		}{
+			{name: "Cmd name", cmd: "PowerOn", id: 1, xmit: "ka 01 01\r", reply: "a 01 OK01x", want: true},
+			{name: "TVCmpMap key", cmd: "PowerOn01", id: 1, xmit: "ka 01 01\r", reply: "a 01 OK01x", want: true},
+			{name: "Ranged key", cmd: "VolSet32", id: 2, xmit: "kf 02 32\r", reply: "f 02 OK32x", want: true},
+			{name: "NG", cmd: "MuteOn", id: 1, xmit: "ke 01 00\r", reply: "e 01 NG00x", want: false},
+			{name: "High set ID", cmd: "PowerOff", id: 42, xmit: "ka 2a 00\r", reply: "a 2a OK00x", want: true},
+			{name: "Out of range", cmd: "VolSet65", id: 1, errMsg: "VolSet: 101 is out of range 0..100"},
+			{name: "WebOS only", cmd: "Home", id: 1, errMsg: `"Home" has no serial command`},
+			{name: "Unknown", cmd: "Bogus", id: 1, errMsg: `unknown command "Bogus"`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				p := &fakePort{replies: map[string]string{tt.xmit: tt.reply}}
+				s := &Serial{MaxID: MaxSetID, ReadTimeout: time.Second, conn: p}
+
+				got, err := s.Xmit(context.Background(), tt.id, tt.cmd)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					So(p.tx, ShouldBeEmpty)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(got, ShouldEqual, tt.want)
+				So(p.tx, ShouldResemble, []string{tt.xmit})
+			})
+		}
+	})
+}
+
+// The table approach pays for every frame of every set up front; the
+// encoder pays per command sent.
+
+func BenchmarkSetSerialCmds(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Cmd.SetSerialCmds()
+	}
+}
+
+func BenchmarkSerialCmdsFor99(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Cmd.SerialCmdsFor(MaxSetID)
+	}
+}
+
+func BenchmarkRespMapFor99(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Cmd.RespMapFor(MaxSetID)
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	l := Cmd["VolSet"]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Encode(l, i%MaxSetID, i%100); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeKey(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, l, v, err := parseKey("VolSet32")
+		if err == nil {
+			_, err = l.frame("VolSet", i%MaxSetID, v)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	l, resp := Cmd["VolLvl"], []byte("f 01 OK1Ex")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(l, 1, resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
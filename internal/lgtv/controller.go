@@ -0,0 +1,221 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Controller sends named Cmd entries, e.g. "MuteOn", to a TV set.
+type Controller interface {
+	Do(ctx context.Context, name string) error
+}
+
+// Transport identifies the path a command travels to a TV set.
+type Transport int
+
+// Transports a Remote can use.
+const (
+	SerialTransport Transport = iota + 1
+	WebOSTransport
+)
+
+// Remote is a Controller for a TV set wired to RS-232C, networked, or both.
+// Do picks whichever transport can carry the named command, trying them
+// in Order.
+type Remote struct {
+	Serial *Serial
+	WebOS  *WebOS
+
+	// Failover retries a command on the next transport able to carry it
+	// when the serial attempt times out or answers NG, or the network
+	// attempt is refused.
+	Failover bool
+
+	// Order lists the transports in the order they're tried; nil means
+	// serial first.
+	Order []Transport
+}
+
+// Result records what happened to a command sent by a Remote.
+type Result struct {
+	Name  string
+	Err   error
+	Tried []Transport
+
+	// Via is the transport that delivered the command, zero if none did.
+	Via Transport
+}
+
+// UnsupportedError reports a command that a transport can't carry.
+type UnsupportedError struct {
+	Name      string
+	Transport string
+}
+
+var (
+	_ Controller = (*Remote)(nil)
+	_ Controller = (*Serial)(nil)
+	_ Controller = (*WebOS)(nil)
+
+	errRefused = errors.New("TV did not accept the command")
+)
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%q is unsupported on this transport (%s)", e.Name, e.Transport)
+}
+
+func (t Transport) String() string {
+	switch t {
+	case SerialTransport:
+		return "serial"
+	case WebOSTransport:
+		return "WebOS"
+	}
+	return "none"
+}
+
+// isSerial reports whether l is a fixed RS-232C command.
+func (l LGCmd) isSerial() bool {
+	return l.Cmd1 != "" && l.Cmd2 != "" && l.Data != "" && l.Data != "FF"
+}
+
+// isWeb reports whether l has a WebOS key code. The table can't tell a
+// zero code from a missing one, so zero counts as missing.
+func (l LGCmd) isWeb() bool {
+	return l.Web > 0
+}
+
+func lookup(name string) (LGCmd, error) {
+	l, ok := Cmd[name]
+	if !ok {
+		return l, fmt.Errorf("unknown command %q", name)
+	}
+	return l, nil
+}
+
+// Do sends the named command to TV set s.ID, or every set on the chain if
+// s.ID is BroadcastID.
+func (s *Serial) Do(ctx context.Context, name string) error {
+	l, err := lookup(name)
+	if err != nil {
+		return err
+	}
+	if !l.isSerial() {
+		return &UnsupportedError{Name: name, Transport: SerialTransport.String()}
+	}
+
+	if s.ID == BroadcastID {
+		acks, err := s.Broadcast(ctx, name)
+		if err != nil {
+			return err
+		}
+		for _, a := range acks {
+			if !a.OK {
+				return fmt.Errorf("%s: TV set %d: %w", name, a.ID, ErrNG)
+			}
+		}
+		return nil
+	}
+
+	_, err = s.ask(ctx, s.ID, name, l, 0)
+	return err
+}
+
+// Do sends the named command's key code to the TV.
+func (w *WebOS) Do(ctx context.Context, name string) error {
+	l, err := lookup(name)
+	if err != nil {
+		return err
+	}
+	if !l.isWeb() {
+		return &UnsupportedError{Name: name, Transport: WebOSTransport.String()}
+	}
+
+	// POWER is a toggle, and a TV in standby can't hear it anyway
+	if name == "PowerOn" {
+		if err := w.PowerOn(ctx); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+
+	err = w.Retry.do(ctx, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return w.zap(ctx, l.Web)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: key code %d: %w", name, l.Web, err)
+	}
+	return nil
+}
+
+// Do sends the named command over the first transport able to carry it.
+func (r *Remote) Do(ctx context.Context, name string) error {
+	return r.Send(ctx, name).Err
+}
+
+// Send sends the named command and reports which transport delivered it.
+func (r *Remote) Send(ctx context.Context, name string) Result {
+	res := Result{Name: name}
+
+	l, err := lookup(name)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	for _, t := range r.order() {
+		var c Controller
+		switch {
+		case t == SerialTransport && r.Serial != nil && l.isSerial():
+			c = r.Serial
+		case t == WebOSTransport && r.WebOS != nil && l.isWeb():
+			c = r.WebOS
+		default:
+			continue
+		}
+
+		res.Tried = append(res.Tried, t)
+		if res.Err = c.Do(ctx, name); res.Err == nil {
+			res.Via = t
+			return res
+		}
+		if !r.Failover || !failover(res.Err) || ctx.Err() != nil {
+			return res
+		}
+	}
+
+	if res.Tried == nil {
+		res.Err = &UnsupportedError{Name: name, Transport: r.transports()}
+	}
+	return res
+}
+
+func (r *Remote) order() []Transport {
+	if r.Order == nil {
+		return []Transport{SerialTransport, WebOSTransport}
+	}
+	return r.Order
+}
+
+func (r *Remote) transports() string {
+	var s []string
+	for _, t := range r.order() {
+		if (t == SerialTransport && r.Serial != nil) || (t == WebOSTransport && r.WebOS != nil) {
+			s = append(s, t.String())
+		}
+	}
+	if s == nil {
+		return Transport(0).String()
+	}
+	return strings.Join(s, " and ")
+}
+
+// failover reports whether err is worth retrying on another transport.
+func failover(err error) bool {
+	return errors.Is(err, ErrTimeout) || errors.Is(err, ErrNG) || errors.Is(err, errRefused)
+}
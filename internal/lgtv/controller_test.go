@@ -0,0 +1,161 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	logging "github.com/op/go-logging"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestControllerDo(t *testing.T) {
+	Convey("Testing Do()", t, func() {
+		newSerial := func() *Serial {
+			return &Serial{
+				ID:          1,
+				ReadTimeout: time.Second,
+				conn: &fakePort{replies: map[string]string{
+					"ke 01 00\r": "e 01 OK00x",
+					"ka 01 00\r": "a 01 NG00x",
+				}},
+			}
+		}
+
+		tests := []struct {
+			name   string
+			c      Controller
+			cmd    string
+			errMsg string
+			unsupp bool
+			wantNG bool
+		}{
+			{name: "Serial OK", c: newSerial(), cmd: "MuteOn"},
+			{name: "Serial NG", c: newSerial(), cmd: "PowerOff", wantNG: true, errMsg: "PowerOff: TV set answered NG"},
+			{name: "Serial can't carry key code", c: newSerial(), cmd: "Home", unsupp: true, errMsg: `"Home" is unsupported on this transport (serial)`},
+			{name: "Serial can't carry read command", c: newSerial(), cmd: "VolLvl", unsupp: true},
+			{name: "WebOS can't carry serial only command", c: &WebOS{}, cmd: "ScreenOff", unsupp: true, errMsg: `"ScreenOff" is unsupported on this transport (WebOS)`},
+			{name: "Unknown command", c: newSerial(), cmd: "Teleport", errMsg: `unknown command "Teleport"`},
+			{name: "Remote routes to serial", c: &Remote{Serial: newSerial(), WebOS: &WebOS{}}, cmd: "MuteOn"},
+			{name: "Remote without WebOS", c: &Remote{Serial: newSerial()}, cmd: "Home", unsupp: true, errMsg: `"Home" is unsupported on this transport (serial)`},
+			{name: "Remote without transports", c: &Remote{}, cmd: "MuteOn", unsupp: true, errMsg: `"MuteOn" is unsupported on this transport (none)`},
+			{name: "Remote with both transports", c: &Remote{Serial: newSerial(), WebOS: &WebOS{}}, cmd: "VolLvl", unsupp: true, errMsg: `"VolLvl" is unsupported on this transport (serial and WebOS)`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				err := tt.c.Do(context.Background(), tt.cmd)
+
+				var u *UnsupportedError
+				So(errors.As(err, &u), ShouldEqual, tt.unsupp)
+				So(errors.Is(err, ErrNG), ShouldEqual, tt.wantNG)
+				switch {
+				case tt.errMsg != "":
+					So(err.Error(), ShouldEqual, tt.errMsg)
+				case !tt.unsupp:
+					So(err, ShouldBeNil)
+				}
+			})
+		}
+	})
+}
+
+func TestRemoteFailover(t *testing.T) {
+	port, _ := hungTV(t)
+
+	Convey("Testing Remote failover", t, func() {
+		// nothing listens on the loopback WebOS port, so the TV refuses
+		offline := &WebOS{Logger: logging.MustGetLogger("test"), IP: net.IPv4(127, 0, 0, 1)}
+		// a TV that takes the connection and never answers times out
+		hung := &WebOS{Logger: logging.MustGetLogger("test"), IP: net.IPv4(127, 0, 0, 1), Port: port, Timeout: 100 * time.Millisecond}
+		serialReplying := func(reply string) *Serial {
+			return &Serial{
+				ID:          1,
+				ReadTimeout: 50 * time.Millisecond,
+				conn:        &fakePort{replies: map[string]string{"ke 01 00\r": reply, "kd 01 00\r": reply}},
+			}
+		}
+
+		tests := []struct {
+			name    string
+			r       *Remote
+			cmd     string
+			wantErr error
+			via     Transport
+			tried   []Transport
+		}{
+			{
+				name:    "Serial NG falls back to WebOS",
+				r:       &Remote{Serial: serialReplying("e 01 NG00x"), WebOS: offline, Failover: true},
+				cmd:     "MuteOn",
+				wantErr: errRefused,
+				tried:   []Transport{SerialTransport, WebOSTransport},
+			},
+			{
+				name:    "Serial timeout falls back to WebOS",
+				r:       &Remote{Serial: serialReplying(""), WebOS: offline, Failover: true},
+				cmd:     "MuteOn",
+				wantErr: errRefused,
+				tried:   []Transport{SerialTransport, WebOSTransport},
+			},
+			{
+				name:    "No failover",
+				r:       &Remote{Serial: serialReplying("e 01 NG00x"), WebOS: offline},
+				cmd:     "MuteOn",
+				wantErr: ErrNG,
+				tried:   []Transport{SerialTransport},
+			},
+			{
+				name:    "Serial only command",
+				r:       &Remote{Serial: serialReplying(""), WebOS: offline, Failover: true},
+				cmd:     "ScreenOff",
+				wantErr: ErrTimeout,
+				tried:   []Transport{SerialTransport},
+			},
+			{
+				name:  "Reverse order falls back to serial",
+				r:     &Remote{Serial: serialReplying("e 01 OK00x"), WebOS: offline, Failover: true, Order: []Transport{WebOSTransport, SerialTransport}},
+				cmd:   "MuteOn",
+				via:   SerialTransport,
+				tried: []Transport{WebOSTransport, SerialTransport},
+			},
+			{
+				name:  "Hung WebOS falls back to serial",
+				r:     &Remote{Serial: serialReplying("e 01 OK00x"), WebOS: hung, Failover: true, Order: []Transport{WebOSTransport, SerialTransport}},
+				cmd:   "MuteOn",
+				via:   SerialTransport,
+				tried: []Transport{WebOSTransport, SerialTransport},
+			},
+			{
+				name:    "Hung WebOS without failover",
+				r:       &Remote{Serial: serialReplying("e 01 OK00x"), WebOS: hung, Order: []Transport{WebOSTransport, SerialTransport}},
+				cmd:     "MuteOn",
+				wantErr: ErrTimeout,
+				tried:   []Transport{WebOSTransport},
+			},
+			{
+				name:  "Serial delivers first time",
+				r:     &Remote{Serial: serialReplying("e 01 OK00x"), WebOS: offline, Failover: true},
+				cmd:   "MuteOn",
+				via:   SerialTransport,
+				tried: []Transport{SerialTransport},
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				res := tt.r.Send(context.Background(), tt.cmd)
+				So(res.Name, ShouldEqual, tt.cmd)
+				So(res.Via, ShouldEqual, tt.via)
+				So(res.Tried, ShouldResemble, tt.tried)
+				if tt.wantErr == nil {
+					So(res.Err, ShouldBeNil)
+					return
+				}
+				So(errors.Is(res.Err, tt.wantErr), ShouldBeTrue)
+			})
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tarm/serial"
+)
+
+var (
+	// detectBauds are the rates LG sets can be configured for, the
+	// factory default first.
+	detectBauds = []int{9600, 115200, 38400, 19200, 4800, 2400}
+
+	detectParities = []serial.Parity{serial.ParityNone, serial.ParityEven, serial.ParityOdd}
+)
+
+// Detect opens the port at each baud rate and parity an LG set can use
+// and keeps the first on which TV set s.ID, or set 1 if s.ID is
+// BroadcastID, acknowledges a PowerStatus probe. An NG counts, since it
+// still proves the line settings. Baud and Parity are left at the
+// settings found, or as they were if Detect fails.
+func (s *Serial) Detect(ctx context.Context) (err error) {
+	defer func(baud int, parity serial.Parity) {
+		if err != nil {
+			s.Baud, s.Parity = baud, parity
+		}
+	}(s.Baud, s.Parity)
+
+	id := s.ID
+	if id == BroadcastID {
+		id = 1
+	}
+
+	for _, baud := range detectBauds {
+		for _, parity := range detectParities {
+			s.Baud, s.Parity = baud, parity
+
+			c, err := s.open()
+			if err != nil {
+				return err
+			}
+			s.setConn(c)
+
+			_, err = s.Query(ctx, id, "PowerStatus")
+			if err == nil || errors.Is(err, ErrNG) {
+				return nil
+			}
+
+			c.Close()
+			s.setConn(nil)
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+	}
+
+	return fmt.Errorf("TV set %d didn't answer at any baud rate and parity", id)
+}
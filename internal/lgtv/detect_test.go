@@ -0,0 +1,68 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tarm/serial"
+)
+
+func TestDetect(t *testing.T) {
+	Convey("Testing Detect()", t, func() {
+		tests := []struct {
+			name    string
+			baud    int
+			parity  serial.Parity
+			reply   string
+			opens   int
+			errMsg  string
+			openErr error
+		}{
+			{name: "Factory default", baud: 9600, parity: serial.ParityNone, reply: "a 01 OK01x", opens: 1},
+			{name: "Non-default rate", baud: 38400, parity: serial.ParityEven, reply: "a 01 OK01x", opens: 8},
+			{name: "NG still locks on", baud: 2400, parity: serial.ParityOdd, reply: "a 01 NG00x", opens: 18},
+			{name: "Nothing answers", opens: 18, errMsg: "TV set 1 didn't answer at any baud rate and parity"},
+			{name: "Device missing", openErr: errors.New("no such device"), errMsg: "no such device"},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				var (
+					opens int
+					s     = &Serial{Baud: 1200, Parity: serial.ParityMark, ReadTimeout: 20 * time.Millisecond}
+				)
+				s.Opener = func() (io.ReadWriteCloser, error) {
+					if tt.openErr != nil {
+						return nil, tt.openErr
+					}
+					opens++
+
+					// Frames sent at the wrong settings arrive as garbage
+					p := &fakePort{replies: map[string]string{"ka 01 FF\r": "\xfe\x80x"}}
+					if s.Baud == tt.baud && s.Parity == tt.parity {
+						p.replies["ka 01 FF\r"] = tt.reply
+					}
+					return p, nil
+				}
+				defer s.Close()
+
+				err := s.Detect(context.Background())
+				So(opens, ShouldEqual, tt.opens)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					So(s.Do(context.Background(), "PowerOn"), ShouldEqual, errNotOpen)
+					So(s.Baud, ShouldEqual, 1200)
+					So(s.Parity, ShouldEqual, serial.ParityMark)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(s.Baud, ShouldEqual, tt.baud)
+				So(s.Parity, ShouldEqual, tt.parity)
+			})
+		}
+	})
+}
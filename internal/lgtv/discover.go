@@ -0,0 +1,260 @@
+package lgtv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Discovery protocols reported in TVInfo.Protocol
+const (
+	ProtoUDAP = "udap"
+	ProtoSSDP = "ssdp"
+)
+
+var (
+	// udapTargets and ssdpTargets are where Discover sends its searches;
+	// tests point them at emulators.
+	udapTargets = []string{"255.255.255.255:1990"}
+	ssdpTargets = []string{"239.255.255.250:1900"}
+
+	// bSearch asks LG's UDAP service to announce itself
+	bSearch = []byte(`B-SEARCH * HTTP/1.1` + cr +
+		`HOST: 239.255.255.250:1990` + cr +
+		`MAN: "ssdp:discover"` + cr +
+		`MX: 3` + cr +
+		`ST: urn:schemas-udap:service:smartText:1` + cr +
+		`USER-AGENT:` + agent + cr + cr)
+
+	// mSearch asks UPnP media renderers, which newer sets are, to announce
+	// themselves
+	mSearch = []byte(`M-SEARCH * HTTP/1.1` + cr +
+		`HOST: 239.255.255.250:1900` + cr +
+		`MAN: "ssdp:discover"` + cr +
+		`MX: 2` + cr +
+		`ST: urn:schemas-upnp-org:device:MediaRenderer:1` + cr + cr)
+)
+
+// TVInfo describes a networked TV that answered Discover.
+type TVInfo struct {
+	IP       net.IP `json:"ip"`
+	Name     string `json:"name"` // last word of Server: the model over UDAP, e.g. 42LW5700
+	Protocol string `json:"protocol"`
+	MAC      string `json:"mac,omitempty"` // from the ARP table, if it has an entry
+	Server   string `json:"server,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+// TVList is a set of TVs found by Discover.
+type TVList []TVInfo
+
+func (l TVList) String() string {
+	b, _ := json.MarshalIndent(l, "", "\t")
+	return string(b)
+}
+
+// search is a socket to search from and where to send its searches.
+type search struct {
+	from       *localAddr // nil for the default route
+	conn       *net.UDPConn
+	udap, ssdp []*net.UDPAddr
+}
+
+// Discover broadcasts a UDAP B-SEARCH and multicasts an SSDP M-SEARCH, then
+// collects every answer that arrives within timeout. A TV that answers both
+// is listed once for each protocol. Nothing is paired or sent to the TVs
+// found. If ctx ends first, the TVs found so far are returned with its
+// error.
+//
+// The searches leave by whichever interface the kernel picks; see
+// DiscoverOn to choose.
+func Discover(ctx context.Context, timeout time.Duration) (TVList, error) {
+	return DiscoverOn(ctx, timeout)
+}
+
+// DiscoverOn works like Discover, but searches from every address of the
+// interfaces that sel names, e.g. "eth0", or of those within a CIDR, e.g.
+// "192.168.10.0/24". IPv4 searches go to each subnet's broadcast address,
+// and IPv6 ones to the link-local all-nodes and SSDP groups. With no sel
+// it's Discover.
+func DiscoverOn(ctx context.Context, timeout time.Duration, sel ...string) (TVList, error) {
+	searches, err := searchesFor(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sent    bool
+		sendErr error
+	)
+	for i := range searches {
+		s := &searches[i]
+		if s.conn, err = s.listen(); err != nil {
+			break
+		}
+		defer s.conn.Close()
+
+		for _, t := range []struct {
+			addrs []*net.UDPAddr
+			msg   []byte
+		}{{s.udap, bSearch}, {s.ssdp, mSearch}} {
+			for _, a := range t.addrs {
+				// A subnet without multicast routes still takes broadcasts
+				if _, err := s.conn.WriteToUDP(t.msg, a); err != nil {
+					if sendErr == nil {
+						sendErr = err
+					}
+					continue
+				}
+				sent = true
+			}
+		}
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case !sent && sendErr != nil:
+		return nil, sendErr
+	}
+
+	// Wake the reads below when ctx ends
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			for _, s := range searches {
+				s.conn.SetReadDeadline(time.Now())
+			}
+		case <-done:
+		}
+	}()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[string]TVInfo)
+	)
+	deadline := time.Now().Add(timeout)
+	for _, s := range searches {
+		s.conn.SetReadDeadline(deadline)
+		wg.Add(1)
+		go func(conn *net.UDPConn) {
+			defer wg.Done()
+			var buf [2048]byte
+			for {
+				n, addr, err := conn.ReadFromUDP(buf[:])
+				if err != nil {
+					return
+				}
+				if tv, ok := parseAnnounce(buf[:n], addr); ok {
+					if mac, err := lookupMAC(tv.IP); err == nil {
+						tv.MAC = mac.String()
+					}
+					mu.Lock()
+					seen[tv.IP.String()+" "+tv.Protocol] = tv
+					mu.Unlock()
+				}
+			}
+		}(s.conn)
+	}
+	wg.Wait()
+
+	tvs := make(TVList, 0, len(seen))
+	for _, tv := range seen {
+		tvs = append(tvs, tv)
+	}
+	sort.Slice(tvs, func(i, j int) bool {
+		if c := bytes.Compare(tvs[i].IP.To16(), tvs[j].IP.To16()); c != 0 {
+			return c < 0
+		}
+		return tvs[i].Protocol < tvs[j].Protocol
+	})
+
+	return tvs, ctx.Err()
+}
+
+// searchesFor returns the searches to make from the addresses sel picks,
+// or from the default route to udapTargets and ssdpTargets if it's empty.
+func searchesFor(sel []string) ([]search, error) {
+	if len(sel) == 0 {
+		var s search
+		for _, t := range []struct {
+			addrs []string
+			to    *[]*net.UDPAddr
+		}{{udapTargets, &s.udap}, {ssdpTargets, &s.ssdp}} {
+			for _, a := range t.addrs {
+				ua, err := net.ResolveUDPAddr(udp4, a)
+				if err != nil {
+					return nil, err
+				}
+				*t.to = append(*t.to, ua)
+			}
+		}
+		return []search{s}, nil
+	}
+
+	addrs, err := selectAddrs(sel...)
+	if err != nil {
+		return nil, err
+	}
+
+	searches := make([]search, len(addrs))
+	for i := range addrs {
+		udap, ssdp := addrs[i].searchTargets()
+		searches[i] = search{from: &addrs[i], udap: []*net.UDPAddr{udap}, ssdp: []*net.UDPAddr{ssdp}}
+	}
+	return searches, nil
+}
+
+// listen opens the socket to search from.
+func (s *search) listen() (*net.UDPConn, error) {
+	if s.from == nil {
+		return net.ListenUDP(udp4, &net.UDPAddr{})
+	}
+
+	network := udp4
+	if s.from.Net.IP.To4() == nil {
+		network = "udp6"
+	}
+	c, err := net.ListenUDP(network, s.from.udpAddr())
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", s.from, err)
+	}
+	if err := multicastFrom(c, *s.from); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("%v: %v", s.from, err)
+	}
+	return c, nil
+}
+
+// parseAnnounce decodes a reply to B-SEARCH or M-SEARCH from addr.
+func parseAnnounce(msg []byte, addr *net.UDPAddr) (TVInfo, bool) {
+	tv := TVInfo{IP: addr.IP}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(msg)), nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return tv, false
+	}
+	h := resp.Header
+
+	tv.Server = h.Get("Server")
+	tv.Location = h.Get("Location")
+	if f := strings.Fields(tv.Server); len(f) > 0 {
+		tv.Name = f[len(f)-1]
+	}
+
+	tv.Protocol = ProtoSSDP
+	if strings.Contains(h.Get("St"), "udap") || strings.Contains(tv.Server, "UDAP") {
+		tv.Protocol = ProtoUDAP
+	}
+	return tv, true
+}
@@ -0,0 +1,138 @@
+package lgtv
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// discoverTargets points Discover's searches at addrs until the test ends.
+func discoverTargets(t *testing.T, udap, ssdp []string) {
+	oldUDAP, oldSSDP := udapTargets, ssdpTargets
+	udapTargets, ssdpTargets = udap, ssdp
+	t.Cleanup(func() { udapTargets, ssdpTargets = oldUDAP, oldSSDP })
+}
+
+func TestDiscover(t *testing.T) {
+	living := NewWebOSEmulator("42LW5700", "123456")
+	if err := living.Listen("127.0.0.1:0", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer living.Close()
+
+	lobby := NewWebOSEmulator("55LM8600", "654321")
+	if err := lobby.Listen("127.0.0.2:0", "127.0.0.2:0"); err != nil {
+		t.Skipf("no second loopback address: %v", err)
+	}
+	defer lobby.Close()
+
+	ua, ub := living.UDPAddr().String(), lobby.UDPAddr().String()
+
+	Convey("Testing Discover()", t, func() {
+		Convey("running test: Every TV on both protocols", func() {
+			discoverTargets(t, []string{ua, ub}, []string{ua, ub})
+
+			got, err := Discover(context.Background(), 300*time.Millisecond)
+			So(err, ShouldBeNil)
+			So(got, ShouldHaveLength, 4)
+
+			want := []struct {
+				ip, name, proto, server string
+			}{
+				{"127.0.0.1", "42LW5700", ProtoSSDP, "Linux/2.6.18 UPnP/1.0 42LW5700"},
+				{"127.0.0.1", "42LW5700", ProtoUDAP, "Linux/2.6.18 UDAP/2.0 42LW5700"},
+				{"127.0.0.2", "55LM8600", ProtoSSDP, "Linux/2.6.18 UPnP/1.0 55LM8600"},
+				{"127.0.0.2", "55LM8600", ProtoUDAP, "Linux/2.6.18 UDAP/2.0 55LM8600"},
+			}
+			for i, w := range want {
+				So(got[i].IP.String(), ShouldEqual, w.ip)
+				So(got[i].Name, ShouldEqual, w.name)
+				So(got[i].Protocol, ShouldEqual, w.proto)
+				So(got[i].Server, ShouldEqual, w.server)
+				So(got[i].Location, ShouldStartWith, "http://"+w.ip+":")
+			}
+
+			// Discovery leaves the TVs alone
+			So(living.ShowingPIN(), ShouldBeFalse)
+			So(lobby.ShowingPIN(), ShouldBeFalse)
+		})
+
+		Convey("running test: UDAP only", func() {
+			discoverTargets(t, []string{ua}, nil)
+
+			got, err := Discover(context.Background(), 300*time.Millisecond)
+			So(err, ShouldBeNil)
+			So(got, ShouldHaveLength, 1)
+			So(got[0].Protocol, ShouldEqual, ProtoUDAP)
+		})
+
+		Convey("running test: Nobody home", func() {
+			discoverTargets(t, nil, nil)
+
+			start := time.Now()
+			got, err := Discover(context.Background(), 100*time.Millisecond)
+			So(err, ShouldBeNil)
+			So(got, ShouldBeEmpty)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 100*time.Millisecond)
+		})
+
+		Convey("running test: Cancelled", func() {
+			discoverTargets(t, []string{ua}, nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			got, err := Discover(ctx, 10*time.Second)
+			So(err, ShouldResemble, context.DeadlineExceeded)
+			So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+			So(got, ShouldHaveLength, 1)
+		})
+	})
+}
+
+func TestParseAnnounce(t *testing.T) {
+	from := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 1990}
+
+	Convey("Testing parseAnnounce()", t, func() {
+		tests := []struct {
+			name  string
+			msg   string
+			ok    bool
+			tv    string
+			proto string
+		}{
+			{
+				name:  "UDAP",
+				msg:   "HTTP/1.1 200 OK" + cr + "SERVER: Linux/2.6.18 UDAP/2.0 42LW5700" + cr + "ST: urn:schemas-udap:service:smartText:1" + cr + cr,
+				ok:    true,
+				tv:    "42LW5700",
+				proto: ProtoUDAP,
+			},
+			{
+				name:  "SSDP",
+				msg:   "HTTP/1.1 200 OK" + cr + "SERVER: WebOS/1.5 UPnP/1.0 webOSTV/1.0" + cr + "ST: urn:schemas-upnp-org:device:MediaRenderer:1" + cr + cr,
+				ok:    true,
+				tv:    "webOSTV/1.0",
+				proto: ProtoSSDP,
+			},
+			{name: "Our own search", msg: string(mSearch)},
+			{name: "Error status", msg: "HTTP/1.1 404 Not Found" + cr + cr},
+			{name: "Garbage", msg: "hello"},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				tv, ok := parseAnnounce([]byte(tt.msg), from)
+				So(ok, ShouldEqual, tt.ok)
+				if ok {
+					So(tv.IP.String(), ShouldEqual, "192.168.1.20")
+					So(tv.Name, ShouldEqual, tt.tv)
+					So(tv.Protocol, ShouldEqual, tt.proto)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package lgtv
+
+import "errors"
+
+func setFlowControl(path string, rts, xon bool) error {
+	return errors.New("flow control is unsupported on this platform")
+}
@@ -0,0 +1,37 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package lgtv
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// setFlowControl turns RTS/CTS and XON/XOFF flow control on or off for
+// the terminal at path. Termios settings belong to the device, so they
+// outlast the descriptor opened here to make them.
+func setFlowControl(path string, rts, xon bool) error {
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var t syscall.Termios
+	if err := ioctl(f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&t))); err != nil {
+		return err
+	}
+
+	t.Cflag &^= cflagRTSCTS
+	if rts {
+		t.Cflag |= cflagRTSCTS
+	}
+	t.Iflag &^= syscall.IXON | syscall.IXOFF
+	if xon {
+		t.Iflag |= syscall.IXON | syscall.IXOFF
+	}
+
+	return ioctl(f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&t)))
+}
@@ -0,0 +1,80 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package lgtv
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tarm/serial"
+)
+
+func termios(path string) (syscall.Termios, error) {
+	var t syscall.Termios
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return t, err
+	}
+	defer f.Close()
+	err = ioctl(f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&t)))
+	return t, err
+}
+
+func TestOpenLineSettings(t *testing.T) {
+	m, _, err := openPTY()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	m.Close()
+
+	Convey("Testing Open() line settings", t, func() {
+		tests := []struct {
+			name  string
+			stop  serial.StopBits
+			rts   bool
+			xon   bool
+			cflag uint64
+			iflag uint64
+		}{
+			{name: "Defaults", stop: serial.Stop1},
+			{name: "Two stop bits", stop: serial.Stop2, cflag: syscall.CSTOPB},
+			{name: "RTS/CTS", stop: serial.Stop1, rts: true, cflag: cflagRTSCTS},
+			{name: "XON/XOFF", stop: serial.Stop1, xon: true, iflag: syscall.IXON | syscall.IXOFF},
+			{name: "Everything", stop: serial.Stop2, rts: true, xon: true, cflag: syscall.CSTOPB | cflagRTSCTS, iflag: syscall.IXON | syscall.IXOFF},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				e := NewSerialEmulator(1)
+				path, err := e.ListenPTY()
+				So(err, ShouldBeNil)
+				defer e.Close()
+
+				s := &Serial{
+					Baud:           9600,
+					ID:             1,
+					Port:           path,
+					ReadTimeout:    time.Second,
+					RTSFlowControl: tt.rts,
+					StopBits:       tt.stop,
+					XONFlowControl: tt.xon,
+				}
+				So(s.Open(), ShouldBeNil)
+				defer s.Close()
+
+				tio, err := termios(path)
+				So(err, ShouldBeNil)
+				So(uint64(tio.Cflag)&(syscall.CSTOPB|cflagRTSCTS), ShouldEqual, tt.cflag)
+				So(uint64(tio.Iflag)&(syscall.IXON|syscall.IXOFF), ShouldEqual, tt.iflag)
+
+				So(s.Do(context.Background(), "MuteOn"), ShouldBeNil)
+			})
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package lgtv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrGatewayClosed is returned by Gateway.Serve once Close is called.
+var ErrGatewayClosed = errors.New("gateway is closed")
+
+// Gateway shares one Serial among many network clients. Clients speak the
+// raw LG protocol over TCP, e.g. "ka 01 01\r", and each gets back the
+// acknowledgements to its own frames. Frames from every client go through
+// the Serial's queue, so they reach the TV sets one at a time.
+type Gateway struct {
+	Serial *Serial
+
+	mu     sync.Mutex
+	ln     []net.Listener
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// ListenAndServe listens on the TCP address addr and calls Serve.
+func (g *Gateway) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return g.Serve(l)
+}
+
+// Serve accepts clients on l until Close, handling each in its own
+// goroutine. It always returns an error, ErrGatewayClosed after Close.
+func (g *Gateway) Serve(l net.Listener) error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		l.Close()
+		return ErrGatewayClosed
+	}
+	g.ln = append(g.ln, l)
+	g.mu.Unlock()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if g.closed {
+				return ErrGatewayClosed
+			}
+			return err
+		}
+
+		if !g.track(c) {
+			c.Close()
+			return ErrGatewayClosed
+		}
+		go g.handle(c)
+	}
+}
+
+// Close stops every listener and disconnects every client. It leaves the
+// Serial open.
+func (g *Gateway) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closed = true
+	var err error
+	for _, l := range g.ln {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for c := range g.conns {
+		c.Close()
+	}
+	return err
+}
+
+// track records c as a live client unless the gateway is closed.
+func (g *Gateway) track(c net.Conn) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+	if g.conns == nil {
+		g.conns = make(map[net.Conn]struct{})
+	}
+	g.conns[c] = struct{}{}
+	return true
+}
+
+// handle relays c's frames until it hangs up. A frame that gets no answer,
+// because it's malformed or no set replied in time, is answered with
+// silence, as the TV sets themselves would.
+func (g *Gateway) handle(c net.Conn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		g.mu.Lock()
+		delete(g.conns, c)
+		g.mu.Unlock()
+		c.Close()
+	}()
+
+	sc := bufio.NewScanner(c)
+	sc.Split(splitRaw)
+	for sc.Scan() {
+		resp, err := g.relay(ctx, sc.Text())
+		if err != nil || len(resp) == 0 {
+			continue
+		}
+		if _, err := c.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+// splitRaw is a bufio.SplitFunc like splitFrames that keeps each frame's
+// terminator, so frames reach the port exactly as the client sent them. A
+// frame cut short by the client hanging up is dropped.
+func splitRaw(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), nil, nil
+	}
+	return 0, nil, nil
+}
+
+// relay checks one client frame, sends it to the port unchanged and returns
+// the acknowledgements that answer it.
+func (g *Gateway) relay(ctx context.Context, fr string) ([]byte, error) {
+	_, cmd2, id, _, ok := parseFrame(fr)
+	if !ok {
+		return nil, nil
+	}
+
+	n := 1
+	if id == BroadcastID {
+		n = g.Serial.maxID()
+	}
+
+	resp, err := g.Serial.transact(ctx, []byte(fr), n)
+	if err != nil {
+		return nil, err
+	}
+
+	// The queue only waits for acknowledgements to this frame, but hands
+	// back whatever arrived if none did
+	acks, _ := ScanAcks(resp)
+	var out []byte
+	for _, a := range acks {
+		if a.Cmd2 == cmd2 && (id == BroadcastID || a.ID == id) {
+			out = append(out, a.frame()...)
+		}
+	}
+	return out, nil
+}
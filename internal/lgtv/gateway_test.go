@@ -0,0 +1,162 @@
+package lgtv
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// gatewayClient dials the gateway at addr and returns a function that
+// sends one frame and reads back up to n acknowledgements.
+func gatewayClient(t *testing.T, addr string) func(fr string, n int) []string {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	r := bufio.NewReader(c)
+	return func(fr string, n int) []string {
+		if _, err := c.Write([]byte(fr)); err != nil {
+			t.Fatal(err)
+		}
+
+		var acks []string
+		for len(acks) < n {
+			c.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+			a, err := r.ReadString('x')
+			if err != nil {
+				break
+			}
+			acks = append(acks, a)
+		}
+		return acks
+	}
+}
+
+func TestGateway(t *testing.T) {
+	e := NewSerialEmulator(1, 2, 3)
+	p := &strictPort{emuPort: emuPort{e: e}}
+	s := &Serial{MaxID: 3, ReadTimeout: 200 * time.Millisecond, conn: p}
+	defer s.Close()
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Gateway{Serial: s}
+	done := make(chan error, 1)
+	go func() { done <- g.Serve(l) }()
+	addr := l.Addr().String()
+
+	Convey("Testing Gateway", t, func() {
+		tests := []struct {
+			name  string
+			frame string
+			n     int
+			want  []string
+		}{
+			{name: "LG frame", frame: "ke 01 00\r", n: 1, want: []string{"e 01 OK00x"}},
+			{name: "Set", frame: "kf 02 1e\r", n: 1, want: []string{"f 02 OK1Ex"}},
+			{name: "Spaced frame", frame: "k f 02 14\r", n: 1},
+			{name: "Query", frame: "kf 02 ff\r", n: 1, want: []string{"f 02 OK1Ex"}},
+			{name: "NG", frame: "kf 01 99\r", n: 1, want: []string{"f 01 NG99x"}},
+			{name: "Broadcast", frame: "ka 00 ff\r", n: 3, want: []string{"a 01 OK01x", "a 02 OK01x", "a 03 OK01x"}},
+			{name: "Absent set", frame: "ka 04 ff\r", n: 1},
+			{name: "Garbage", frame: "hello\r", n: 1},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				send := gatewayClient(t, addr)
+				So(send(tt.frame, tt.n), ShouldResemble, tt.want)
+			})
+		}
+
+		Convey("running test: Frames reach the port unchanged", func() {
+			send := gatewayClient(t, addr)
+			for _, fr := range []string{"kf 02 1e\r", "ke 01 00\n"} {
+				So(send(fr, 1), ShouldHaveLength, 1)
+
+				p.mu.Lock()
+				So(p.tx[len(p.tx)-1], ShouldEqual, fr)
+				p.mu.Unlock()
+			}
+		})
+	})
+
+	Convey("Testing Gateway with concurrent clients", t, func() {
+		var (
+			wg  sync.WaitGroup
+			mu  sync.Mutex
+			got = make(map[int][]string)
+		)
+		for id := 1; id <= 3; id++ {
+			send := gatewayClient(t, addr)
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				for v := 10; v < 15; v++ {
+					acks := send(string(frame("k", "f", setID(id), fmt.Sprintf("%02X", v+id))), 1)
+					mu.Lock()
+					got[id] = append(got[id], acks...)
+					mu.Unlock()
+				}
+			}(id)
+		}
+		wg.Wait()
+
+		for id := 1; id <= 3; id++ {
+			So(got[id], ShouldHaveLength, 5)
+			for i, a := range got[id] {
+				So(a, ShouldEqual, fmt.Sprintf("f %s OK%02Xx", setID(id), 10+i+id))
+			}
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		So(p.overlaps, ShouldEqual, 0)
+	})
+
+	Convey("Testing Gateway.Close()", t, func() {
+		So(g.Close(), ShouldBeNil)
+		So(<-done, ShouldEqual, ErrGatewayClosed)
+		So(g.Serve(l), ShouldEqual, ErrGatewayClosed)
+	})
+}
+
+func TestGatewayLateAck(t *testing.T) {
+	// Set 1 answers after ReadTimeout, while set 2's query is waiting
+	p := &latePort{
+		fakePort: fakePort{replies: map[string]string{
+			"ke 01 00\r": "e 01 OK00x",
+			"kf 02 ff\r": "f 02 OK14x",
+		}},
+		delays: []time.Duration{170 * time.Millisecond, 90 * time.Millisecond},
+	}
+	s := &Serial{MaxID: 2, ReadTimeout: 120 * time.Millisecond, conn: p}
+	defer s.Close()
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Gateway{Serial: s}
+	defer g.Close()
+	go g.Serve(l)
+
+	Convey("Testing Gateway with a late acknowledgement", t, func() {
+		send1, send2 := gatewayClient(t, l.Addr().String()), gatewayClient(t, l.Addr().String())
+
+		late := make(chan []string)
+		go func() { late <- send1("ke 01 00\r", 1) }()
+		time.Sleep(20 * time.Millisecond)
+
+		So(send2("kf 02 ff\r", 1), ShouldResemble, []string{"f 02 OK14x"})
+		So(<-late, ShouldBeEmpty)
+	})
+}
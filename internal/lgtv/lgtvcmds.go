@@ -62,6 +62,7 @@ var (
 		"InputAV":         {Cmd1: "k", Cmd2: "b", Data: "02"},
 		"InputCmpnt1":     {Cmd1: "k", Cmd2: "b", Data: "04"},
 		"InputCmpnt2":     {Cmd1: "k", Cmd2: "b", Data: "05"},
+		"InputStatus":     {Cmd1: "k", Cmd2: "b", Data: "FF"},
 		"InputHDMI(DTV)":  {Cmd1: "k", Cmd2: "b", Data: "08"},
 		"InputHDMI(PC)":   {Cmd1: "k", Cmd2: "b", Data: "09"},
 		"InputRGB(DTV)":   {Cmd1: "k", Cmd2: "b", Data: "06"},
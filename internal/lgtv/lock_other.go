@@ -0,0 +1,39 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package lgtv
+
+import (
+	"os"
+	"time"
+)
+
+var (
+	// lockPoll paces attempts to take a held lock
+	lockPoll = 50 * time.Millisecond
+
+	// lockStale is how old a lock file gets before it's taken to belong
+	// to a process that died holding it.
+	lockStale = 30 * time.Second
+)
+
+// lockFile creates path exclusively, waiting while another process holds
+// it, and returns the function that releases it.
+func lockFile(path string) (func() error, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(lockPoll)
+	}
+}
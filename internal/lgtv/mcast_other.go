@@ -0,0 +1,12 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package lgtv
+
+import "net"
+
+// multicastFrom leaves IPv4 multicasts to the routing table; IPv6 ones
+// still go out of the interface their zone names.
+func multicastFrom(c *net.UDPConn, a localAddr) error {
+	return nil
+}
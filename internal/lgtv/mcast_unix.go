@@ -0,0 +1,33 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package lgtv
+
+import (
+	"net"
+	"syscall"
+)
+
+// multicastFrom makes multicasts sent on c leave through a's interface
+// rather than the one the routing table picks.
+func multicastFrom(c *net.UDPConn, a localAddr) error {
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		if ip := a.Net.IP.To4(); ip != nil {
+			var in [4]byte
+			copy(in[:], ip)
+			serr = syscall.SetsockoptInet4Addr(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_IF, in)
+			return
+		}
+		serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_IF, a.Iface.Index)
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
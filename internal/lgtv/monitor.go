@@ -0,0 +1,285 @@
+package lgtv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFrame bounds how much unterminated traffic a Monitor holds on to.
+const maxFrame = 64
+
+// Traffic is one frame seen on a monitored line, decoded as far as the
+// Cmd table allows.
+type Traffic struct {
+	Time time.Time `json:"time"`
+
+	// Dir is "tx" for a command frame, "rx" for an acknowledgement.
+	Dir   string `json:"dir"`
+	Frame string `json:"frame"`
+	ID    int    `json:"id"`
+
+	// Name is the Cmd key of the command, e.g. "MuteOn" or "VolSet".
+	// Acknowledgements are named after the command they answer.
+	Name   string `json:"name,omitempty"`
+	Data   string `json:"data,omitempty"`
+	Value  int    `json:"value"`
+	Status string `json:"status,omitempty"`
+
+	// Key is the Cmd key of an enumerated setting that a read reports,
+	// e.g. "PowerOn" for PowerStatus.
+	Key string `json:"key,omitempty"`
+
+	Err string `json:"error,omitempty"`
+}
+
+// Monitor decodes LG protocol traffic read passively from one or more
+// ports, such as the TX and RX legs of a Y-cable on separate adapters. It
+// tells commands from acknowledgements by their terminators, so it doesn't
+// matter which port carries which direction.
+type Monitor struct {
+	// Out receives one line per frame, as text or as JSON if JSON is set.
+	Out  io.Writer
+	JSON bool
+
+	// Resp resolves acknowledgements to Cmd keys. If it's nil, one is
+	// built from Cmd, growing to cover the highest set ID seen.
+	Resp RespMap
+
+	mu      sync.Mutex
+	ownResp bool
+	bufs    map[int][]byte
+	pending map[string]Traffic // Cmd2 → last command
+}
+
+// Watch reads every port until ctx is done or one of them fails, writing
+// each frame to Out as it completes. Ports that have nothing to read may
+// return io.EOF, as serial ports do when their read timeout expires.
+func (m *Monitor) Watch(ctx context.Context, ports ...io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(ports))
+	for i, p := range ports {
+		go func(i int, p io.Reader) {
+			errs <- m.watch(ctx, i, p)
+		}(i, p)
+	}
+
+	var err error
+	for range ports {
+		if e := <-errs; err == nil && e != nil {
+			err = e
+			cancel()
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// watch reads port i until ctx is done or the port fails.
+func (m *Monitor) watch(ctx context.Context, i int, p io.Reader) error {
+	b := make([]byte, 64)
+	for ctx.Err() == nil {
+		n, err := p.Read(b)
+		if n > 0 {
+			for _, t := range m.feed(i, b[:n], time.Now()) {
+				if werr := m.write(t); werr != nil {
+					return werr
+				}
+			}
+		}
+
+		switch {
+		case err != nil && err != io.EOF:
+			return err
+		case n == 0:
+			time.Sleep(pollInterval)
+		}
+	}
+	return nil
+}
+
+// write prints t to Out.
+func (m *Monitor) write(t Traffic) error {
+	line := t.String()
+	if m.JSON {
+		b, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		line = string(b)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintln(m.Out, line)
+	return err
+}
+
+// feed adds a chunk read from port i at time t and returns the frames it
+// completes. Commands end in a carriage return or line feed and
+// acknowledgements in 'x'; an 'x' that doesn't end a well formed
+// acknowledgement is taken as data, since "x" is also a Cmd1.
+func (m *Monitor) feed(i int, chunk []byte, t time.Time) []Traffic {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.bufs == nil {
+		m.bufs = make(map[int][]byte)
+	}
+
+	var out []Traffic
+	buf := m.bufs[i]
+	for _, c := range chunk {
+		switch c {
+		case '\r', '\n':
+			if fr := strings.TrimSpace(string(buf)); fr != "" {
+				out = append(out, m.command(fr, t))
+			}
+			buf = buf[:0]
+			continue
+		}
+
+		buf = append(buf, c)
+		if c != 'x' {
+			if len(buf) > maxFrame {
+				buf = buf[len(buf)-maxFrame:]
+			}
+			continue
+		}
+		if fr := ackRx.Find(buf); fr != nil {
+			out = append(out, m.ack(string(fr), t))
+			buf = buf[:0]
+		}
+	}
+	m.bufs[i] = buf
+
+	return out
+}
+
+// command decodes a command frame and remembers it for its
+// acknowledgement.
+func (m *Monitor) command(fr string, t time.Time) Traffic {
+	tr := Traffic{Time: t, Dir: "tx", Frame: fr}
+
+	cmd1, cmd2, id, data, ok := parseFrame(fr)
+	if !ok {
+		tr.Err = "malformed frame"
+		return tr
+	}
+	tr.ID, tr.Data = id, data
+	tr.Name, tr.Value = frameName(cmd1, cmd2, data)
+
+	if m.pending == nil {
+		m.pending = make(map[string]Traffic)
+	}
+	m.pending[cmd2] = tr
+	return tr
+}
+
+// ack decodes an acknowledgement, naming it after the last command with
+// the same Cmd2.
+func (m *Monitor) ack(fr string, t time.Time) Traffic {
+	tr := Traffic{Time: t, Dir: "rx", Frame: fr}
+
+	a, err := ParseAck([]byte(fr))
+	if err != nil {
+		tr.Err = err.Error()
+		return tr
+	}
+	tr.ID, tr.Data, tr.Value = a.ID, a.Data, a.Value
+	tr.Status = "NG"
+	if a.OK {
+		tr.Status = "OK"
+	}
+
+	if m.Resp == nil || (m.ownResp && m.Resp[a.ID] == nil) {
+		maxID := MaxTVs
+		if a.ID > maxID {
+			maxID = a.ID
+		}
+		m.Resp, m.ownResp = Cmd.RespMapFor(maxID), true
+	}
+
+	cmd, ok := m.pending[a.Cmd2]
+	if !ok {
+		tr.Name = AckParser{Resp: m.Resp}.key(a)
+		return tr
+	}
+
+	tr.Name = cmd.Name
+	l := Cmd[cmd.Name]
+	if cmd.Data == "FF" {
+		tr.Key = Cmd.enumKey(l, a.Data)
+	} else if k := (AckParser{Cmd1: l.Cmd1, Resp: m.Resp}).key(a); k != cmd.Name {
+		tr.Key = k
+	}
+	return tr
+}
+
+// frameName returns the Cmd key that sends data with cmd1 and cmd2, and the
+// level it sets if the command is ranged.
+func frameName(cmd1, cmd2, data string) (string, int) {
+	keys := make([]string, 0, len(Cmd))
+	for k := range Cmd {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ranged := ""
+	for _, k := range keys {
+		l := Cmd[k]
+		if l.Cmd1 != cmd1 || l.Cmd2 != cmd2 {
+			continue
+		}
+		switch {
+		case l.Max == 0 && l.Data == data:
+			return k, 0
+		case l.Max > 0 && ranged == "":
+			ranged = k
+		}
+	}
+
+	if ranged == "" {
+		return "", 0
+	}
+	v, err := strconv.ParseUint(data, 16, 16)
+	if err != nil || int(v) > Cmd[ranged].limit() {
+		return "", 0
+	}
+	return ranged, int(v)
+}
+
+// String formats t as a log line, e.g.
+//
+//	15:04:05.000 tx 01 VolSet 30          "kf 01 1E"
+//	15:04:05.012 rx 01 OK VolSet 30       "f 01 OK1Ex"
+func (t Traffic) String() string {
+	desc := t.Name
+	if desc == "" {
+		desc = "?"
+	}
+	if l := Cmd[t.Name]; l.Max > 0 || (t.Dir == "rx" && l.Data == "FF" && t.Key == "") {
+		desc += " " + strconv.Itoa(t.Value)
+	}
+	if t.Key != "" {
+		desc += " " + t.Key
+	}
+	if t.Status != "" {
+		desc = t.Status + " " + desc
+	}
+	if t.Err != "" {
+		desc = t.Err
+	}
+
+	return fmt.Sprintf("%s %s %s %-20s %q", t.Time.Format("15:04:05.000"), t.Dir, setID(t.ID), desc, t.Frame)
+}
@@ -0,0 +1,221 @@
+package lgtv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// tapReader plays back chunks like a serial port, returning io.EOF once
+// it has nothing more to read, then err if it's set.
+type tapReader struct {
+	mu     sync.Mutex
+	chunks []string
+	err    error
+}
+
+func (r *tapReader) Read(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.chunks) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		return 0, io.EOF
+	}
+	n := copy(b, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+// syncBuffer is a bytes.Buffer that's safe to read while a Monitor writes.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (w *syncBuffer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.Write(p)
+}
+
+func (w *syncBuffer) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.String()
+}
+
+func TestMonitorFeed(t *testing.T) {
+	now := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	Convey("Testing Monitor decoding", t, func() {
+		type chunk struct {
+			port int
+			data string
+		}
+		tests := []struct {
+			name   string
+			chunks []chunk
+			want   []Traffic
+		}{
+			{
+				name:   "Command and OK",
+				chunks: []chunk{{0, "ke 01 00\r"}, {1, "e 01 OK00x"}},
+				want: []Traffic{
+					{Dir: "tx", Frame: "ke 01 00", ID: 1, Name: "MuteOn", Data: "00"},
+					{Dir: "rx", Frame: "e 01 OK00x", ID: 1, Name: "MuteOn", Data: "00", Status: "OK"},
+				},
+			},
+			{
+				name:   "Ranged command and NG",
+				chunks: []chunk{{0, "kf 02 1E\r"}, {1, "f 02 NG1Ex"}},
+				want: []Traffic{
+					{Dir: "tx", Frame: "kf 02 1E", ID: 2, Name: "VolSet", Data: "1E", Value: 30},
+					{Dir: "rx", Frame: "f 02 NG1Ex", ID: 2, Name: "VolSet", Data: "1E", Value: 30, Status: "NG"},
+				},
+			},
+			{
+				name:   "Read of an enumerated setting",
+				chunks: []chunk{{0, "ka 01 ff\r"}, {1, "a 01 OK01x"}},
+				want: []Traffic{
+					{Dir: "tx", Frame: "ka 01 ff", ID: 1, Name: "PowerStatus", Data: "FF"},
+					{Dir: "rx", Frame: "a 01 OK01x", ID: 1, Name: "PowerStatus", Data: "01", Value: 1, Status: "OK", Key: "PowerOn"},
+				},
+			},
+			{
+				name:   "Frames split across reads on one port",
+				chunks: []chunk{{0, "kf 0"}, {0, "1 ff\rf 01 OK"}, {0, "14x"}},
+				want: []Traffic{
+					{Dir: "tx", Frame: "kf 01 ff", ID: 1, Name: "VolLvl", Data: "FF"},
+					{Dir: "rx", Frame: "f 01 OK14x", ID: 1, Name: "VolLvl", Data: "14", Value: 20, Status: "OK"},
+				},
+			},
+			{
+				name:   "Unsolicited ack",
+				chunks: []chunk{{1, "noise e 03 OK01x"}},
+				want: []Traffic{
+					{Dir: "rx", Frame: "e 03 OK01x", ID: 3, Name: "MuteOff", Data: "01", Value: 1, Status: "OK"},
+				},
+			},
+			{
+				name:   "Set beyond MaxTVs",
+				chunks: []chunk{{1, "e 0a OK01x"}},
+				want: []Traffic{
+					{Dir: "rx", Frame: "e 0a OK01x", ID: 10, Name: "MuteOff", Data: "01", Value: 1, Status: "OK"},
+				},
+			},
+			{
+				name:   "Cmd1 x isn't a terminator",
+				chunks: []chunk{{0, "xb 01 90\r"}},
+				want:   []Traffic{{Dir: "tx", Frame: "xb 01 90", ID: 1, Data: "90"}},
+			},
+			{
+				name:   "Malformed command",
+				chunks: []chunk{{0, "hello\r\n"}},
+				want:   []Traffic{{Dir: "tx", Frame: "hello", Err: "malformed frame"}},
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				m := &Monitor{}
+				var got []Traffic
+				for _, c := range tt.chunks {
+					got = append(got, m.feed(c.port, []byte(c.data), now)...)
+				}
+				for i := range tt.want {
+					tt.want[i].Time = now
+				}
+				So(got, ShouldResemble, tt.want)
+			})
+		}
+	})
+}
+
+func TestTrafficString(t *testing.T) {
+	now := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	Convey("Testing Traffic.String()", t, func() {
+		tests := []struct {
+			t    Traffic
+			want string
+		}{
+			{
+				t:    Traffic{Dir: "tx", Frame: "kf 01 1E", ID: 1, Name: "VolSet", Value: 30},
+				want: `15:04:05.000 tx 01 VolSet 30            "kf 01 1E"`,
+			},
+			{
+				t:    Traffic{Dir: "rx", Frame: "f 01 OK14x", ID: 1, Name: "VolLvl", Value: 20, Status: "OK"},
+				want: `15:04:05.000 rx 01 OK VolLvl 20         "f 01 OK14x"`,
+			},
+			{
+				t:    Traffic{Dir: "rx", Frame: "a 01 OK01x", ID: 1, Name: "PowerStatus", Value: 1, Status: "OK", Key: "PowerOn"},
+				want: `15:04:05.000 rx 01 OK PowerStatus PowerOn "a 01 OK01x"`,
+			},
+			{
+				t:    Traffic{Dir: "tx", Frame: "xb 01 90", ID: 1},
+				want: `15:04:05.000 tx 01 ?                    "xb 01 90"`,
+			},
+			{
+				t:    Traffic{Dir: "tx", Frame: "hello", Err: "malformed frame"},
+				want: `15:04:05.000 tx 00 malformed frame      "hello"`,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.want, func() {
+				tt.t.Time = now
+				So(tt.t.String(), ShouldEqual, tt.want)
+			})
+		}
+	})
+}
+
+func TestMonitorWatch(t *testing.T) {
+	Convey("Testing Monitor.Watch()", t, func() {
+		Convey("running test: Two ports as JSON lines", func() {
+			var out syncBuffer
+			m := &Monitor{Out: &out, JSON: true}
+			tx := &tapReader{chunks: []string{"ke 01 00\r"}}
+			rx := &tapReader{chunks: []string{"", "", "", "", "", "e 01 OK00x"}}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			So(m.Watch(ctx, tx, rx), ShouldResemble, context.DeadlineExceeded)
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			So(lines, ShouldHaveLength, 2)
+			var got []Traffic
+			for _, l := range lines {
+				var tr Traffic
+				So(json.Unmarshal([]byte(l), &tr), ShouldBeNil)
+				So(tr.Time.IsZero(), ShouldBeFalse)
+				tr.Time = time.Time{}
+				got = append(got, tr)
+			}
+			So(got, ShouldResemble, []Traffic{
+				{Dir: "tx", Frame: "ke 01 00", ID: 1, Name: "MuteOn", Data: "00"},
+				{Dir: "rx", Frame: "e 01 OK00x", ID: 1, Name: "MuteOn", Data: "00", Status: "OK"},
+			})
+		})
+
+		Convey("running test: A failing port stops the monitor", func() {
+			var out syncBuffer
+			m := &Monitor{Out: &out}
+			unplugged := errors.New("device unplugged")
+
+			err := m.Watch(context.Background(), &tapReader{}, &tapReader{chunks: []string{"ka 01 01\r"}, err: unplugged})
+			So(err, ShouldEqual, unplugged)
+			So(out.String(), ShouldContainSubstring, `tx 01 PowerOn`)
+		})
+	})
+}
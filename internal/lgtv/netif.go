@@ -0,0 +1,128 @@
+package lgtv
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+var (
+	// interfaces and ifaceAddrs list this host's network interfaces;
+	// tests replace them.
+	interfaces = net.Interfaces
+	ifaceAddrs = func(ifi *net.Interface) ([]net.Addr, error) { return ifi.Addrs() }
+
+	// udapPort and ssdpPort are where TVs listen for searches
+	udapPort = 1990
+	ssdpPort = 1900
+
+	// Multicast groups searched on IPv4 and, link-local, on IPv6
+	ssdpGroup4 = net.IPv4(239, 255, 255, 250)
+	allNodes6  = net.ParseIP("ff02::1")
+	ssdpGroup6 = net.ParseIP("ff02::c")
+)
+
+// localAddr is an address of ours that discovery can search from.
+type localAddr struct {
+	Iface net.Interface
+	Net   *net.IPNet
+}
+
+// selectAddrs returns the addresses of the interfaces that sel names,
+// either by name, e.g. "eth0", or by a CIDR their addresses fall in, e.g.
+// "192.168.10.0/24" or "fe80::/10". Interfaces that are down are skipped,
+// and so is loopback unless it's named. IPv6 addresses other than
+// link-local ones are left out, as that's the scope TVs advertise in.
+func selectAddrs(sel ...string) ([]localAddr, error) {
+	ifs, err := interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []localAddr
+	for _, s := range sel {
+		var (
+			cidr  *net.IPNet
+			found bool
+		)
+		if strings.Contains(s, "/") {
+			if _, cidr, err = net.ParseCIDR(s); err != nil {
+				return nil, fmt.Errorf("bad interface selector %q: %v", s, err)
+			}
+		}
+
+		for _, ifi := range ifs {
+			if ifi.Flags&net.FlagUp == 0 || (cidr == nil && ifi.Name != s) {
+				continue
+			}
+			if cidr != nil && ifi.Flags&net.FlagLoopback != 0 && !cidr.IP.IsLoopback() {
+				continue
+			}
+
+			addrs, err := ifaceAddrs(&ifi)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range addrs {
+				ipn, ok := a.(*net.IPNet)
+				switch {
+				case !ok:
+				case cidr != nil && !cidr.Contains(ipn.IP):
+				case ipn.IP.To4() == nil && !ipn.IP.IsLinkLocalUnicast():
+				default:
+					out = append(out, localAddr{Iface: ifi, Net: ipn})
+					found = true
+				}
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no usable address on interface %q", s)
+		}
+	}
+
+	return out, nil
+}
+
+// udpAddr returns the address, zoned to its interface if it's IPv6.
+func (a localAddr) udpAddr() *net.UDPAddr {
+	ua := &net.UDPAddr{IP: a.Net.IP}
+	if a.Net.IP.To4() == nil {
+		ua.Zone = a.Iface.Name
+	}
+	return ua
+}
+
+// broadcast returns the subnet-directed broadcast address of an IPv4
+// address, or nil for IPv6, which has none.
+func (a localAddr) broadcast() net.IP {
+	ip := a.Net.IP.To4()
+	if ip == nil {
+		return nil
+	}
+
+	mask := a.Net.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	b := make(net.IP, net.IPv4len)
+	for i := range ip {
+		b[i] = ip[i] | ^mask[i]
+	}
+	return b
+}
+
+// searchTargets returns where to send the UDAP and SSDP searches from a:
+// the subnet's broadcast address and the SSDP group on IPv4, the all-nodes
+// and SSDP link-local groups on IPv6.
+func (a localAddr) searchTargets() (udap, ssdp *net.UDPAddr) {
+	if b := a.broadcast(); b != nil {
+		return &net.UDPAddr{IP: b, Port: udapPort}, &net.UDPAddr{IP: ssdpGroup4, Port: ssdpPort}
+	}
+	zone := a.Iface.Name
+	return &net.UDPAddr{IP: allNodes6, Port: udapPort, Zone: zone}, &net.UDPAddr{IP: ssdpGroup6, Port: ssdpPort, Zone: zone}
+}
+
+func (a localAddr) String() string {
+	return a.Iface.Name + " " + a.Net.String()
+}
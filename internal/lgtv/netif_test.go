@@ -0,0 +1,153 @@
+package lgtv
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeInterfaces makes selectAddrs see a loopback, two VLANs, a wireless
+// interface that's down and a dual-stack interface until the test ends.
+func fakeInterfaces(t *testing.T) {
+	cidr := func(s string) *net.IPNet {
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.IP = ip
+		return n
+	}
+
+	ifs := []net.Interface{
+		{Index: 1, Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+		{Index: 2, Name: "eth0.10", Flags: net.FlagUp | net.FlagBroadcast | net.FlagMulticast},
+		{Index: 3, Name: "eth0.20", Flags: net.FlagUp | net.FlagBroadcast | net.FlagMulticast},
+		{Index: 4, Name: "wlan0", Flags: net.FlagBroadcast | net.FlagMulticast},
+		{Index: 5, Name: "eth1", Flags: net.FlagUp | net.FlagBroadcast | net.FlagMulticast},
+	}
+	addrs := map[string][]net.Addr{
+		"lo":      {cidr("127.0.0.1/8"), cidr("::1/128")},
+		"eth0.10": {cidr("192.168.10.5/24")},
+		"eth0.20": {cidr("10.20.0.9/16")},
+		"wlan0":   {cidr("192.168.50.2/24")},
+		"eth1":    {cidr("172.16.4.1/22"), cidr("2001:db8::1/64"), cidr("fe80::1c2d:3eff:fe4f:5a6b/64")},
+	}
+
+	oldIfs, oldAddrs := interfaces, ifaceAddrs
+	interfaces = func() ([]net.Interface, error) { return ifs, nil }
+	ifaceAddrs = func(ifi *net.Interface) ([]net.Addr, error) { return addrs[ifi.Name], nil }
+	t.Cleanup(func() { interfaces, ifaceAddrs = oldIfs, oldAddrs })
+}
+
+func TestSelectAddrs(t *testing.T) {
+	fakeInterfaces(t)
+
+	Convey("Testing selectAddrs()", t, func() {
+		tests := []struct {
+			name   string
+			sel    []string
+			want   []string
+			udap   []string
+			ssdp   []string
+			errMsg string
+		}{
+			{
+				name: "By name",
+				sel:  []string{"eth0.10"},
+				want: []string{"eth0.10 192.168.10.5/24"},
+				udap: []string{"192.168.10.255:1990"},
+				ssdp: []string{"239.255.255.250:1900"},
+			},
+			{
+				name: "By CIDR",
+				sel:  []string{"10.20.0.0/16"},
+				want: []string{"eth0.20 10.20.0.9/16"},
+				udap: []string{"10.20.255.255:1990"},
+				ssdp: []string{"239.255.255.250:1900"},
+			},
+			{
+				name: "Dual stack keeps link-local IPv6 only",
+				sel:  []string{"eth1"},
+				want: []string{"eth1 172.16.4.1/22", "eth1 fe80::1c2d:3eff:fe4f:5a6b/64"},
+				udap: []string{"172.16.7.255:1990", "[ff02::1%eth1]:1990"},
+				ssdp: []string{"239.255.255.250:1900", "[ff02::c%eth1]:1900"},
+			},
+			{
+				name: "Several at once",
+				sel:  []string{"eth0.10", "eth0.20"},
+				want: []string{"eth0.10 192.168.10.5/24", "eth0.20 10.20.0.9/16"},
+				udap: []string{"192.168.10.255:1990", "10.20.255.255:1990"},
+				ssdp: []string{"239.255.255.250:1900", "239.255.255.250:1900"},
+			},
+			{
+				name: "Every link-local IPv6 address",
+				sel:  []string{"fe80::/10"},
+				want: []string{"eth1 fe80::1c2d:3eff:fe4f:5a6b/64"},
+				udap: []string{"[ff02::1%eth1]:1990"},
+				ssdp: []string{"[ff02::c%eth1]:1900"},
+			},
+			{
+				name: "Loopback only by name",
+				sel:  []string{"lo"},
+				want: []string{"lo 127.0.0.1/8"},
+				udap: []string{"127.255.255.255:1990"},
+				ssdp: []string{"239.255.255.250:1900"},
+			},
+			{name: "Interface down", sel: []string{"wlan0"}, errMsg: `no usable address on interface "wlan0"`},
+			{name: "No such interface", sel: []string{"eth9"}, errMsg: `no usable address on interface "eth9"`},
+			{name: "Bad CIDR", sel: []string{"192.168.10.0/33"}, errMsg: `bad interface selector "192.168.10.0/33": invalid CIDR address: 192.168.10.0/33`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				got, err := selectAddrs(tt.sel...)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+
+				var names, udap, ssdp []string
+				for _, a := range got {
+					names = append(names, a.String())
+					u, s := a.searchTargets()
+					udap, ssdp = append(udap, u.String()), append(ssdp, s.String())
+				}
+				So(names, ShouldResemble, tt.want)
+				So(udap, ShouldResemble, tt.udap)
+				So(ssdp, ShouldResemble, tt.ssdp)
+			})
+		}
+	})
+}
+
+func TestDiscoverOn(t *testing.T) {
+	// Subnet broadcasts only reach sockets bound to the wildcard address
+	e := NewWebOSEmulator("42LW5700", "123456")
+	if err := e.Listen("0.0.0.0:0", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	oldUDAP, oldSSDP := udapPort, ssdpPort
+	udapPort, ssdpPort = e.UDPAddr().Port, e.UDPAddr().Port
+	defer func() { udapPort, ssdpPort = oldUDAP, oldSSDP }()
+
+	Convey("Testing DiscoverOn()", t, func() {
+		Convey("running test: Loopback's subnet broadcast", func() {
+			got, err := DiscoverOn(context.Background(), 300*time.Millisecond, "lo")
+			So(err, ShouldBeNil)
+			So(got, ShouldNotBeEmpty)
+			So(got[0].IP.String(), ShouldEqual, "127.0.0.1")
+			So(got[0].Name, ShouldEqual, "42LW5700")
+		})
+
+		Convey("running test: No such interface", func() {
+			_, err := DiscoverOn(context.Background(), 300*time.Millisecond, "nope0")
+			So(err.Error(), ShouldEqual, `no usable address on interface "nope0"`)
+		})
+	})
+}
@@ -0,0 +1,269 @@
+package lgtv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+var (
+	// dialTimeout bounds connecting to a network serial server
+	dialTimeout = 5 * time.Second
+
+	// netPoll is how long a network read waits for data before returning
+	// none, like a serial port's read timeout.
+	netPoll = 50 * time.Millisecond
+)
+
+// Telnet commands and the COM-PORT-OPTION of RFC 2217
+const (
+	tnSE   = 240
+	tnSB   = 250
+	tnWILL = 251
+	tnWONT = 252
+	tnDO   = 253
+	tnDONT = 254
+	tnIAC  = 255
+
+	comPortOption = 44
+	cpSetBaud     = 1
+	cpSetDataSize = 2
+	cpSetParity   = 3
+	cpSetStopSize = 4
+	cpSetControl  = 5
+)
+
+// netPort is a serial line reached over TCP, e.g. through ser2net or an
+// IP-to-RS232 gateway.
+type netPort struct {
+	conn net.Conn
+}
+
+// rfc2217Port is a netPort that speaks telnet with the RFC 2217
+// COM-PORT-OPTION, so the line settings are made on the server.
+type rfc2217Port struct {
+	netPort
+	tn telnetFilter
+}
+
+// telnetFilter strips telnet commands out of a byte stream, keeping its
+// state between reads.
+type telnetFilter struct {
+	state int
+	cmd   byte
+}
+
+const (
+	tsData = iota
+	tsIAC
+	tsOption
+	tsSB
+	tsSBIAC
+)
+
+// isNetPort reports whether port names a network serial server, i.e.
+// "tcp://host:port" or "rfc2217://host:port".
+func isNetPort(port string) bool {
+	return strings.HasPrefix(port, "tcp://") || strings.HasPrefix(port, "rfc2217://")
+}
+
+// dial connects to the network serial server that Port names.
+func (s *Serial) dial() (io.ReadWriteCloser, error) {
+	u, err := url.Parse(s.Port)
+	if err != nil {
+		return nil, err
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("%s: missing port number", s.Port)
+	}
+
+	conn, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "tcp" {
+		return &netPort{conn: conn}, nil
+	}
+
+	p := &rfc2217Port{netPort: netPort{conn: conn}}
+	setup, err := comPortSetup(s.Baud, s.Parity, s.StopBits, s.RTSFlowControl, s.XONFlowControl)
+	if err == nil {
+		_, err = conn.Write(setup)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Read returns whatever arrives within netPoll, which may be nothing.
+func (p *netPort) Read(b []byte) (int, error) {
+	p.conn.SetReadDeadline(time.Now().Add(netPoll))
+	n, err := p.conn.Read(b)
+
+	var nerr net.Error
+	switch {
+	case errors.As(err, &nerr) && nerr.Timeout():
+		return n, nil
+	case err == io.EOF:
+		return n, fmt.Errorf("%s closed the connection", p.conn.RemoteAddr())
+	}
+	return n, err
+}
+
+func (p *netPort) Write(b []byte) (int, error) {
+	return p.conn.Write(b)
+}
+
+func (p *netPort) Close() error {
+	return p.conn.Close()
+}
+
+// Read returns the serial data that arrives within netPoll, answering
+// any telnet negotiation along the way.
+func (p *rfc2217Port) Read(b []byte) (int, error) {
+	raw := make([]byte, len(b))
+	n, err := p.netPort.Read(raw)
+
+	data, reply := p.tn.filter(raw[:n])
+	if len(reply) > 0 {
+		if _, werr := p.conn.Write(reply); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return copy(b, data), err
+}
+
+// Write sends b as telnet data, doubling any IAC bytes.
+func (p *rfc2217Port) Write(b []byte) (int, error) {
+	if _, err := p.conn.Write(escapeIAC(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// filter returns the data bytes of in and any replies owed to the server.
+// We offer COM-PORT-OPTION and refuse every other option.
+func (f *telnetFilter) filter(in []byte) ([]byte, []byte) {
+	var data, reply []byte
+
+	for _, c := range in {
+		switch f.state {
+		case tsData:
+			if c == tnIAC {
+				f.state = tsIAC
+				continue
+			}
+			data = append(data, c)
+
+		case tsIAC:
+			switch c {
+			case tnIAC:
+				data = append(data, c)
+				f.state = tsData
+			case tnWILL, tnWONT, tnDO, tnDONT:
+				f.cmd, f.state = c, tsOption
+			case tnSB:
+				f.state = tsSB
+			default:
+				f.state = tsData
+			}
+
+		case tsOption:
+			switch {
+			case c == comPortOption:
+			case f.cmd == tnDO:
+				reply = append(reply, tnIAC, tnWONT, c)
+			case f.cmd == tnWILL:
+				reply = append(reply, tnIAC, tnDONT, c)
+			}
+			f.state = tsData
+
+		case tsSB:
+			// Subnegotiations are the server confirming our settings
+			if c == tnIAC {
+				f.state = tsSBIAC
+			}
+
+		case tsSBIAC:
+			f.state = tsSB
+			if c == tnSE {
+				f.state = tsData
+			}
+		}
+	}
+
+	return data, reply
+}
+
+// escapeIAC doubles IAC bytes so telnet passes them as data.
+func escapeIAC(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == tnIAC {
+			out = append(out, tnIAC)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// comPortSetup returns the telnet commands that offer COM-PORT-OPTION and
+// set the server's line to the given settings.
+func comPortSetup(baud int, parity serial.Parity, stop serial.StopBits, rts, xon bool) ([]byte, error) {
+	b := []byte{tnIAC, tnWILL, comPortOption}
+	sub := func(cmd byte, v ...byte) {
+		b = append(b, tnIAC, tnSB, comPortOption, cmd)
+		b = append(b, escapeIAC(v)...)
+		b = append(b, tnIAC, tnSE)
+	}
+
+	var rate [4]byte
+	binary.BigEndian.PutUint32(rate[:], uint32(baud))
+	sub(cpSetBaud, rate[:]...)
+	sub(cpSetDataSize, 8)
+
+	par, ok := map[serial.Parity]byte{
+		0:                  1,
+		serial.ParityNone:  1,
+		serial.ParityOdd:   2,
+		serial.ParityEven:  3,
+		serial.ParityMark:  4,
+		serial.ParitySpace: 5,
+	}[parity]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parity %q", parity)
+	}
+	sub(cpSetParity, par)
+
+	st, ok := map[serial.StopBits]byte{
+		0:                1,
+		serial.Stop1:     1,
+		serial.Stop2:     2,
+		serial.Stop1Half: 3,
+	}[stop]
+	if !ok {
+		return nil, fmt.Errorf("unsupported stop bits %d", stop)
+	}
+	sub(cpSetStopSize, st)
+
+	control := byte(1)
+	switch {
+	case rts:
+		control = 3
+	case xon:
+		control = 2
+	}
+	sub(cpSetControl, control)
+
+	return b, nil
+}
@@ -0,0 +1,299 @@
+package lgtv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tarm/serial"
+)
+
+// listenTCP hands every connection on a loopback listener to serve and
+// returns the listener's address.
+func listenTCP(t *testing.T, serve func(net.Conn)) string {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestNetPortTCP(t *testing.T) {
+	e := NewSerialEmulator(1)
+
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	addr := listenTCP(t, func(c net.Conn) {
+		mu.Lock()
+		conns = append(conns, c)
+		mu.Unlock()
+		e.Serve(c)
+		c.Close()
+	})
+
+	Convey("Testing Serial over tcp://", t, func() {
+		ctx := context.Background()
+		s := &Serial{
+			ID:           1,
+			Port:         "tcp://" + addr,
+			ReadTimeout:  time.Second,
+			Reconnect:    true,
+			ReconnectMin: 10 * time.Millisecond,
+		}
+		So(s.Open(), ShouldBeNil)
+		defer s.Close()
+
+		So(s.Do(ctx, "MuteOn"), ShouldBeNil)
+		r, err := s.Query(ctx, 1, "VolLvl")
+		So(err, ShouldBeNil)
+		So(r.Value, ShouldEqual, 20)
+
+		// The server drops the connection
+		mu.Lock()
+		conns[0].Close()
+		mu.Unlock()
+
+		err = s.Do(ctx, "MuteOff")
+		So(errors.Is(err, ErrPortClosed), ShouldBeTrue)
+
+		deadline := time.Now().Add(time.Second)
+		for err != nil && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			err = s.Do(ctx, "MuteOff")
+		}
+		So(err, ShouldBeNil)
+		v, _ := e.Value(1, "MuteStatus")
+		So(v, ShouldEqual, "01")
+	})
+
+	Convey("Testing tcp:// without a port number", t, func() {
+		s := &Serial{Port: "tcp://127.0.0.1"}
+		So(s.Open().Error(), ShouldEqual, "tcp://127.0.0.1: missing port number")
+	})
+}
+
+func TestTelnetFilter(t *testing.T) {
+	Convey("Testing telnetFilter", t, func() {
+		tests := []struct {
+			name   string
+			chunks []string
+			data   string
+			reply  string
+		}{
+			{name: "Plain data", chunks: []string{"a 01 OK01x"}, data: "a 01 OK01x"},
+			{name: "Escaped IAC", chunks: []string{"a\xff\xffb"}, data: "a\xffb"},
+			{name: "IAC split across reads", chunks: []string{"a\xff", "\xffb"}, data: "a\xffb"},
+			{name: "DO COM-PORT-OPTION", chunks: []string{"\xff\xfd\x2ca"}, data: "a"},
+			{name: "Other DO refused", chunks: []string{"\xff\xfd\x18a"}, data: "a", reply: "\xff\xfc\x18"},
+			{name: "Other WILL refused", chunks: []string{"\xff\xfb\x01", "a"}, data: "a", reply: "\xff\xfe\x01"},
+			{name: "WONT needs no reply", chunks: []string{"\xff\xfc\x01a"}, data: "a"},
+			{
+				name:   "Subnegotiation dropped",
+				chunks: []string{"a\xff\xfa\x2c\x65\x00\x00\x25", "\x80\xff\xf0b"},
+				data:   "ab",
+			},
+			{name: "IAC IAC inside subnegotiation", chunks: []string{"\xff\xfa\x2c\x01\xff\xff\xff\xf0a"}, data: "a"},
+			{name: "NOP", chunks: []string{"a\xff\xf1b"}, data: "ab"},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				var (
+					f           telnetFilter
+					data, reply []byte
+				)
+				for _, c := range tt.chunks {
+					d, r := f.filter([]byte(c))
+					data, reply = append(data, d...), append(reply, r...)
+				}
+				So(string(data), ShouldEqual, tt.data)
+				So(string(reply), ShouldEqual, tt.reply)
+			})
+		}
+	})
+}
+
+func TestComPortSetup(t *testing.T) {
+	Convey("Testing comPortSetup()", t, func() {
+		sb := func(cmd byte, v ...byte) string {
+			return string(append(append([]byte{tnIAC, tnSB, comPortOption, cmd}, v...), tnIAC, tnSE))
+		}
+		will := "\xff\xfb\x2c"
+
+		tests := []struct {
+			name   string
+			baud   int
+			parity serial.Parity
+			stop   serial.StopBits
+			rts    bool
+			xon    bool
+			want   string
+			errMsg string
+		}{
+			{
+				name: "LG default",
+				baud: 9600,
+				want: will + sb(1, 0, 0, 0x25, 0x80) + sb(2, 8) + sb(3, 1) + sb(4, 1) + sb(5, 1),
+			},
+			{
+				name:   "115200 even, two stop bits, RTS/CTS",
+				baud:   115200,
+				parity: serial.ParityEven,
+				stop:   serial.Stop2,
+				rts:    true,
+				want:   will + sb(1, 0, 1, 0xC2, 0) + sb(2, 8) + sb(3, 3) + sb(4, 2) + sb(5, 3),
+			},
+			{
+				name:   "Odd parity, XON/XOFF",
+				baud:   2400,
+				parity: serial.ParityOdd,
+				xon:    true,
+				want:   will + sb(1, 0, 0, 0x09, 0x60) + sb(2, 8) + sb(3, 2) + sb(4, 1) + sb(5, 2),
+			},
+			{name: "Bad parity", baud: 9600, parity: 'X', errMsg: `unsupported parity 'X'`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				got, err := comPortSetup(tt.baud, tt.parity, tt.stop, tt.rts, tt.xon)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(string(got), ShouldEqual, tt.want)
+			})
+		}
+	})
+}
+
+// rfc2217Server is a minimal RFC 2217 server in front of a SerialEmulator.
+// It records the client's subnegotiations and option replies.
+type rfc2217Server struct {
+	e       *SerialEmulator
+	mu      sync.Mutex
+	subs    [][]byte
+	options []byte
+}
+
+func (srv *rfc2217Server) serve(c net.Conn) {
+	defer c.Close()
+
+	// Ask for the option and offer one the client should refuse
+	c.Write([]byte{tnIAC, tnDO, comPortOption, tnIAC, tnWILL, 1})
+
+	var (
+		buf   = make([]byte, 256)
+		frame []byte
+		sub   []byte
+		state int
+	)
+	for {
+		n, err := c.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, b := range buf[:n] {
+			switch state {
+			case tsData:
+				switch b {
+				case tnIAC:
+					state = tsIAC
+				case '\r':
+					// Confirm nothing useful, then answer in telnet data
+					reply := []byte(srv.e.Reply(string(frame)))
+					c.Write(append([]byte{tnIAC, tnSB, comPortOption, 101, 0, tnIAC, tnSE}, escapeIAC(reply)...))
+					frame = nil
+				default:
+					frame = append(frame, b)
+				}
+			case tsIAC:
+				switch b {
+				case tnSB:
+					state, sub = tsSB, nil
+				case tnIAC:
+					frame, state = append(frame, b), tsData
+				default:
+					srv.mu.Lock()
+					srv.options = append(srv.options, b)
+					srv.mu.Unlock()
+					state = tsOption
+				}
+			case tsOption:
+				srv.mu.Lock()
+				srv.options = append(srv.options, b)
+				srv.mu.Unlock()
+				state = tsData
+			case tsSB:
+				if b == tnIAC {
+					state = tsSBIAC
+					continue
+				}
+				sub = append(sub, b)
+			case tsSBIAC:
+				if b == tnSE {
+					srv.mu.Lock()
+					srv.subs = append(srv.subs, sub)
+					srv.mu.Unlock()
+					state = tsData
+					continue
+				}
+				sub, state = append(sub, b), tsSB
+			}
+		}
+	}
+}
+
+func TestNetPortRFC2217(t *testing.T) {
+	srv := &rfc2217Server{e: NewSerialEmulator(1)}
+	addr := listenTCP(t, srv.serve)
+
+	Convey("Testing Serial over rfc2217://", t, func() {
+		ctx := context.Background()
+		s := &Serial{
+			Baud:        19200,
+			ID:          1,
+			Parity:      serial.ParityEven,
+			Port:        "rfc2217://" + addr,
+			ReadTimeout: time.Second,
+		}
+		So(s.Open(), ShouldBeNil)
+		defer s.Close()
+
+		So(s.Do(ctx, "MuteOn"), ShouldBeNil)
+		So(s.SetVolume(ctx, 1, 30), ShouldBeNil)
+		r, err := s.Query(ctx, 1, "VolLvl")
+		So(err, ShouldBeNil)
+		So(r.Value, ShouldEqual, 30)
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		So(srv.subs, ShouldResemble, [][]byte{
+			{comPortOption, 1, 0, 0, 0x4B, 0},
+			{comPortOption, 2, 8},
+			{comPortOption, 3, 3},
+			{comPortOption, 4, 1},
+			{comPortOption, 5, 1},
+		})
+		So(bytes.HasPrefix(srv.options, []byte{tnWILL, comPortOption}), ShouldBeTrue)
+		So(bytes.Contains(srv.options, []byte{tnDONT, 1}), ShouldBeTrue)
+	})
+}
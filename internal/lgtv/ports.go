@@ -0,0 +1,103 @@
+package lgtv
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// devRoot and sysfsRoot are where ListPorts looks for devices and
+	// their identities; tests point them at a fake tree.
+	devRoot   = "/dev"
+	sysfsRoot = "/sys"
+)
+
+// PortInfo describes a serial port and the USB adapter behind it, if any.
+type PortInfo struct {
+	Device       string `json:"device"`
+	VendorID     string `json:"vendor_id,omitempty"`
+	ProductID    string `json:"product_id,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty"`
+
+	// ByID lists the /dev/serial/by-id links to Device, which survive
+	// re-enumeration.
+	ByID []string `json:"by_id,omitempty"`
+}
+
+// PortList is a set of serial ports found by ListPorts.
+type PortList []PortInfo
+
+// ListPorts returns the serial ports on this host with their identities.
+func ListPorts() (PortList, error) {
+	return listPorts()
+}
+
+func (p PortList) String() string {
+	b, _ := json.MarshalIndent(p, "", "\t")
+	return string(b)
+}
+
+// ResolvePort returns the device path that sel picks out. sel is one of:
+//
+//	/dev/ttyUSB0                  a device path, used as is
+//	serial:A6008isP               a USB adapter's serial number
+//	usb:0403:6001                 a USB vendor and product ID
+//	/dev/serial/by-id/*FTDI*      a glob matching a single device
+//
+// Serial numbers and IDs are resolved through sysfs, so only on Linux.
+func ResolvePort(sel string) (string, error) {
+	var match func(PortInfo) bool
+
+	switch {
+	case strings.HasPrefix(sel, "serial:"):
+		sn := strings.TrimPrefix(sel, "serial:")
+		match = func(p PortInfo) bool { return p.Serial == sn }
+
+	case strings.HasPrefix(sel, "usb:"):
+		id := strings.SplitN(strings.TrimPrefix(sel, "usb:"), ":", 2)
+		if len(id) != 2 {
+			return "", fmt.Errorf("bad port selector %q: want usb:VID:PID", sel)
+		}
+		match = func(p PortInfo) bool {
+			return strings.EqualFold(p.VendorID, id[0]) && strings.EqualFold(p.ProductID, id[1])
+		}
+
+	case strings.ContainsAny(sel, "*?["):
+		paths, err := filepath.Glob(sel)
+		if err != nil {
+			return "", fmt.Errorf("bad port selector %q: %v", sel, err)
+		}
+		return only(sel, paths)
+
+	default:
+		return sel, nil
+	}
+
+	ports, err := ListPorts()
+	if err != nil {
+		return "", err
+	}
+
+	var paths []string
+	for _, p := range ports {
+		if match(p) {
+			paths = append(paths, p.Device)
+		}
+	}
+	return only(sel, paths)
+}
+
+// only returns the single path that sel matched.
+func only(sel string, paths []string) (string, error) {
+	switch len(paths) {
+	case 0:
+		return "", fmt.Errorf("no serial port matches %q", sel)
+	case 1:
+		return paths[0], nil
+	}
+	return "", fmt.Errorf("%q matches %d serial ports: %s", sel, len(paths), strings.Join(paths, ", "))
+}
@@ -0,0 +1,81 @@
+package lgtv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// listPorts finds the ttys in sysfs with a device behind them, skipping
+// the 8250 UARTs the kernel registers whether or not they're fitted.
+func listPorts() (PortList, error) {
+	class := filepath.Join(sysfsRoot, "class", "tty")
+	ttys, err := ioutil.ReadDir(class)
+	if err != nil {
+		return nil, err
+	}
+
+	links := byIDLinks()
+
+	var ports PortList
+	for _, fi := range ttys {
+		dev, err := filepath.EvalSymlinks(filepath.Join(class, fi.Name(), "device"))
+		if err != nil {
+			continue // virtual consoles and ptys have no device
+		}
+		if drv, err := filepath.EvalSymlinks(filepath.Join(dev, "driver")); err == nil && filepath.Base(drv) == "serial8250" {
+			continue
+		}
+
+		p := PortInfo{Device: filepath.Join(devRoot, fi.Name())}
+		if usb := usbDevice(dev); usb != "" {
+			p.VendorID = sysfsAttr(usb, "idVendor")
+			p.ProductID = sysfsAttr(usb, "idProduct")
+			p.Serial = sysfsAttr(usb, "serial")
+			p.Manufacturer = sysfsAttr(usb, "manufacturer")
+			p.Product = sysfsAttr(usb, "product")
+		}
+		p.ByID = links[p.Device]
+
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// usbDevice returns the sysfs directory of the USB device that dev, a
+// tty's device directory, belongs to, or "" if it isn't on USB.
+func usbDevice(dev string) string {
+	root, err := filepath.EvalSymlinks(sysfsRoot)
+	if err != nil {
+		return ""
+	}
+
+	for d := dev; strings.HasPrefix(d, root+string(filepath.Separator)); d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "idVendor")); err == nil {
+			return d
+		}
+	}
+	return ""
+}
+
+func sysfsAttr(dir, name string) string {
+	b, _ := ioutil.ReadFile(filepath.Join(dir, name))
+	return strings.TrimSpace(string(b))
+}
+
+// byIDLinks maps device paths to the /dev/serial/by-id links naming them.
+func byIDLinks() map[string][]string {
+	links := make(map[string][]string)
+	paths, _ := filepath.Glob(filepath.Join(devRoot, "serial", "by-id", "*"))
+	for _, p := range paths {
+		dev, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			continue
+		}
+		// Report the device under devRoot even if /dev is itself a link
+		dev = filepath.Join(devRoot, filepath.Base(dev))
+		links[dev] = append(links[dev], p)
+	}
+	return links
+}
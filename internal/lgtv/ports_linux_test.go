@@ -0,0 +1,160 @@
+package lgtv
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeSysfs builds a sysfs and /dev tree with two FTDI-style adapters, a
+// pair of identical Prolific adapters, a CDC ACM board, an 8250 UART and
+// a virtual console, and points ListPorts at it until the test ends.
+func fakeSysfs(t *testing.T) string {
+	root := t.TempDir()
+	sys, dev := filepath.Join(root, "sys"), filepath.Join(root, "dev")
+
+	mkdir := func(p string) {
+		if err := os.MkdirAll(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(p, s string) {
+		mkdir(filepath.Dir(p))
+		if err := ioutil.WriteFile(p, []byte(s+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(target, p string) {
+		mkdir(filepath.Dir(p))
+		if err := os.Symlink(target, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usb := func(port, vid, pid, serial, product, tty, ttyDir string) {
+		d := filepath.Join(sys, "devices/pci0000:00/usb1", port)
+		write(filepath.Join(d, "idVendor"), vid)
+		write(filepath.Join(d, "idProduct"), pid)
+		if serial != "" {
+			write(filepath.Join(d, "serial"), serial)
+		}
+		write(filepath.Join(d, "product"), product)
+		mkdir(filepath.Join(d, ttyDir))
+		link(filepath.Join(d, ttyDir), filepath.Join(sys, "class/tty", tty, "device"))
+		write(filepath.Join(dev, tty), "")
+	}
+
+	usb("1-1", "0403", "6001", "A6008isP", "FT232R USB UART", "ttyUSB0", "1-1:1.0/ttyUSB0")
+	usb("1-2", "067b", "2303", "", "USB-Serial Controller", "ttyUSB1", "1-2:1.0/ttyUSB1")
+	usb("1-3", "067b", "2303", "", "USB-Serial Controller", "ttyUSB2", "1-3:1.0/ttyUSB2")
+	usb("1-4", "2341", "0043", "7563", "Arduino Uno", "ttyACM0", "1-4:1.0")
+	write(filepath.Join(sys, "devices/pci0000:00/usb1/1-1/manufacturer"), "FTDI")
+
+	uart := filepath.Join(sys, "devices/platform/serial8250")
+	mkdir(filepath.Join(sys, "bus/platform/drivers/serial8250"))
+	link(filepath.Join(sys, "bus/platform/drivers/serial8250"), filepath.Join(uart, "driver"))
+	link(uart, filepath.Join(sys, "class/tty/ttyS0/device"))
+	mkdir(filepath.Join(sys, "class/tty/tty0"))
+
+	link("../../ttyUSB0", filepath.Join(dev, "serial/by-id/usb-FTDI_FT232R_USB_UART_A6008isP-if00-port0"))
+
+	oldDev, oldSys := devRoot, sysfsRoot
+	devRoot, sysfsRoot = dev, sys
+	t.Cleanup(func() { devRoot, sysfsRoot = oldDev, oldSys })
+
+	return root
+}
+
+func TestListPorts(t *testing.T) {
+	root := fakeSysfs(t)
+	dev := filepath.Join(root, "dev")
+
+	Convey("Testing ListPorts()", t, func() {
+		got, err := ListPorts()
+		So(err, ShouldBeNil)
+		So(got, ShouldResemble, PortList{
+			{Device: dev + "/ttyACM0", VendorID: "2341", ProductID: "0043", Serial: "7563", Product: "Arduino Uno"},
+			{
+				Device:       dev + "/ttyUSB0",
+				VendorID:     "0403",
+				ProductID:    "6001",
+				Serial:       "A6008isP",
+				Manufacturer: "FTDI",
+				Product:      "FT232R USB UART",
+				ByID:         []string{dev + "/serial/by-id/usb-FTDI_FT232R_USB_UART_A6008isP-if00-port0"},
+			},
+			{Device: dev + "/ttyUSB1", VendorID: "067b", ProductID: "2303", Product: "USB-Serial Controller"},
+			{Device: dev + "/ttyUSB2", VendorID: "067b", ProductID: "2303", Product: "USB-Serial Controller"},
+		})
+	})
+}
+
+func TestResolvePort(t *testing.T) {
+	root := fakeSysfs(t)
+	dev := filepath.Join(root, "dev")
+
+	Convey("Testing ResolvePort()", t, func() {
+		tests := []struct {
+			sel    string
+			want   string
+			errMsg string
+		}{
+			{sel: "/dev/ttys000", want: "/dev/ttys000"},
+			{sel: "serial:A6008isP", want: dev + "/ttyUSB0"},
+			{sel: "serial:7563", want: dev + "/ttyACM0"},
+			{sel: "usb:0403:6001", want: dev + "/ttyUSB0"},
+			{sel: "usb:2341:0043", want: dev + "/ttyACM0"},
+			{sel: dev + "/serial/by-id/*FTDI*", want: dev + "/serial/by-id/usb-FTDI_FT232R_USB_UART_A6008isP-if00-port0"},
+			{sel: "serial:nope", errMsg: `no serial port matches "serial:nope"`},
+			{sel: "usb:0403", errMsg: `bad port selector "usb:0403": want usb:VID:PID`},
+			{sel: "usb:067b:2303", errMsg: `"usb:067b:2303" matches 2 serial ports: ` + dev + "/ttyUSB1, " + dev + "/ttyUSB2"},
+			{sel: dev + "/ttyUSB*", errMsg: `"` + dev + `/ttyUSB*" matches 3 serial ports: ` + dev + "/ttyUSB0, " + dev + "/ttyUSB1, " + dev + "/ttyUSB2"},
+			{sel: dev + "/serial/by-id/*Prolific*", errMsg: `no serial port matches "` + dev + `/serial/by-id/*Prolific*"`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.sel, func() {
+				got, err := ResolvePort(tt.sel)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(got, ShouldEqual, tt.want)
+			})
+		}
+	})
+}
+
+func TestOpenSelector(t *testing.T) {
+	root := fakeSysfs(t)
+
+	e := NewSerialEmulator(1)
+	path, err := e.ListenPTY()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	defer e.Close()
+
+	// The adapter's device node is the emulator's pty
+	tty := filepath.Join(root, "dev/ttyUSB0")
+	if err := os.Remove(tty); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(path, tty); err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Testing Open() with a port selector", t, func() {
+		s := &Serial{Baud: 9600, ID: 1, Port: "serial:A6008isP", ReadTimeout: time.Second}
+		So(s.Open(), ShouldBeNil)
+		defer s.Close()
+
+		So(s.Do(context.Background(), "MuteOn"), ShouldBeNil)
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package lgtv
+
+import "errors"
+
+func listPorts() (PortList, error) {
+	return nil, errors.New("listing serial ports needs Linux sysfs")
+}
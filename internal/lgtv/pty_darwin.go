@@ -0,0 +1,38 @@
+package lgtv
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+
+	// cflagRTSCTS is CCTS_OFLOW|CRTS_IFLOW, which package syscall leaves out
+	cflagRTSCTS = 0x30000
+)
+
+// openPTY opens a pseudo-terminal and returns its master and slave path.
+func openPTY() (*os.File, string, error) {
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var name [128]byte
+	for _, req := range []uintptr{syscall.TIOCPTYGRANT, syscall.TIOCPTYUNLK} {
+		if err := ioctl(m.Fd(), req, 0); err != nil {
+			m.Close()
+			return nil, "", err
+		}
+	}
+	if err := ioctl(m.Fd(), syscall.TIOCPTYGNAME, uintptr(unsafe.Pointer(&name[0]))); err != nil {
+		m.Close()
+		return nil, "", err
+	}
+
+	return m, string(name[:bytes.IndexByte(name[:], 0)]), nil
+}
@@ -0,0 +1,38 @@
+package lgtv
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+
+	// cflagRTSCTS is CRTSCTS, which package syscall leaves out
+	cflagRTSCTS = 0x80000000
+)
+
+// openPTY opens a pseudo-terminal and returns its master and slave path.
+func openPTY() (*os.File, string, error) {
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var unlock int32
+	if err := ioctl(m.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		m.Close()
+		return nil, "", err
+	}
+
+	var n uint32
+	if err := ioctl(m.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		m.Close()
+		return nil, "", err
+	}
+
+	return m, fmt.Sprintf("/dev/pts/%d", n), nil
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package lgtv
+
+import (
+	"errors"
+	"os"
+)
+
+func openPTY() (*os.File, string, error) {
+	return nil, "", errors.New("pseudo-terminals are unsupported on this platform")
+}
+
+func makeRaw(f *os.File) error {
+	return nil
+}
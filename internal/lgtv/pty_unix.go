@@ -0,0 +1,36 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package lgtv
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// makeRaw turns off echo, line editing and character translation on a
+// terminal so frames pass through untouched.
+func makeRaw(f *os.File) error {
+	var t syscall.Termios
+	if err := ioctl(f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&t))); err != nil {
+		return err
+	}
+
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	t.Oflag &^= syscall.OPOST
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB
+	t.Cflag |= syscall.CS8
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+
+	return ioctl(f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&t)))
+}
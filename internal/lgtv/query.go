@@ -0,0 +1,67 @@
+package lgtv
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Reading is a TV set's decoded reply to an "FF" read command.
+type Reading struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+
+	// Value holds levels, hours and degrees Celsius.
+	Value int `json:"value"`
+
+	// Key holds the matching Cmd key of enumerated settings, e.g. "PowerOn".
+	Key string `json:"key,omitempty"`
+}
+
+// Query sends the read command name, e.g. "VolLvl", to TV set id and
+// decodes the current value from its acknowledgement.
+func (s *Serial) Query(ctx context.Context, id int, name string) (Reading, error) {
+	r := Reading{Name: name}
+
+	l, ok := Cmd[name]
+	if !ok || l.Cmd1 == "" || l.Data != "FF" {
+		return r, fmt.Errorf("%q is not a read command", name)
+	}
+
+	a, err := s.ask(ctx, id, name, l, 0)
+	if err != nil {
+		return r, err
+	}
+
+	r.Data = a.Data
+	r.Value = a.Value
+	r.Key = Cmd.enumKey(l, a.Data)
+
+	return r, nil
+}
+
+// String returns the setting's Cmd key, or its raw data if it has none.
+func (r Reading) String() string {
+	if r.Key != "" {
+		return r.Key
+	}
+	return r.Data
+}
+
+// enumKey returns the key of the fixed setting that read command l reports
+// with data, or "" if the setting isn't enumerated.
+func (tv TVCmds) enumKey(l LGCmd, data string) string {
+	keys := make([]string, 0, len(tv))
+	for k := range tv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := tv[k]
+		if v.Cmd2 == l.Cmd2 && (v.Cmd1 == l.Cmd1 || v.Cmd1 == "") && v.Max == 0 && v.Data == data {
+			return k
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQuery(t *testing.T) {
+	Convey("Testing Query()", t, func() {
+		tests := []struct {
+			name   string
+			query  string
+			xmit   string
+			reply  string
+			want   Reading
+			errMsg string
+		}{
+			{
+				name:  "Power",
+				query: "PowerStatus",
+				xmit:  "ka 01 FF\r",
+				reply: "a 01 OK01x",
+				want:  Reading{Name: "PowerStatus", Data: "01", Value: 1, Key: "PowerOn"},
+			},
+			{
+				name:  "Aspect",
+				query: "AspectStatus",
+				xmit:  "kc 01 FF\r",
+				reply: "c 01 OK02x",
+				want:  Reading{Name: "AspectStatus", Data: "02", Value: 2, Key: "Aspect16:9"},
+			},
+			{
+				name:  "Colour temperature",
+				query: "ColorTempLvl",
+				xmit:  "ku 01 FF\r",
+				reply: "u 01 OK02x",
+				want:  Reading{Name: "ColorTempLvl", Data: "02", Value: 2, Key: "ColorWarm"},
+			},
+			{
+				name:  "Volume",
+				query: "VolLvl",
+				xmit:  "kf 01 FF\r",
+				reply: "f 01 OK1Ex",
+				want:  Reading{Name: "VolLvl", Data: "1E", Value: 30},
+			},
+			{
+				name:  "Temperature",
+				query: "InternalTemp",
+				xmit:  "dn 01 FF\r",
+				reply: "n 01 OK28x",
+				want:  Reading{Name: "InternalTemp", Data: "28", Value: 40},
+			},
+			{
+				name:  "Hours",
+				query: "TimeElapsed",
+				xmit:  "dl 01 FF\r",
+				reply: "l 01 OK04D2x",
+				want:  Reading{Name: "TimeElapsed", Data: "04D2", Value: 1234},
+			},
+			{
+				name:   "NG",
+				query:  "BrightLevel",
+				xmit:   "kh 01 FF\r",
+				reply:  "h 01 NG00x",
+				errMsg: "BrightLevel: TV set answered NG",
+			},
+			{
+				name:   "Wrong set answered",
+				query:  "VolLvl",
+				xmit:   "kf 01 FF\r",
+				reply:  "f 02 OK1Ex",
+				errMsg: `malformed frame "f 02 OK1Ex": no acknowledgement for VolLvl`,
+			},
+			{
+				name:   "Not a read command",
+				query:  "PowerOn",
+				errMsg: `"PowerOn" is not a read command`,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				s := &Serial{
+					ReadTimeout: time.Second,
+					conn:        &fakePort{replies: map[string]string{tt.xmit: tt.reply}},
+				}
+
+				got, err := s.Query(context.Background(), 1, tt.query)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, tt.want)
+			})
+		}
+
+		Convey("running test: NG is distinguishable", func() {
+			s := &Serial{
+				ReadTimeout: time.Second,
+				conn:        &fakePort{replies: map[string]string{"ka 01 FF\r": "a 01 NG00x"}},
+			}
+			_, err := s.Query(context.Background(), 1, "PowerStatus")
+			So(errors.Is(err, ErrNG), ShouldBeTrue)
+		})
+	})
+}
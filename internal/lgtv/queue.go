@@ -0,0 +1,218 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// job is one command waiting its turn on the port.
+type job struct {
+	ctx   context.Context
+	frame []byte
+	n     int
+	res   chan ack
+}
+
+type ack struct {
+	resp []byte
+	err  error
+}
+
+// Close stops the command queue and closes the port. Commands still
+// waiting for their turn fail.
+func (s *Serial) Close() error {
+	s.run()
+	s.stop.Do(func() { close(s.quit) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// transact queues frame for the port and returns the first n
+// acknowledgements that answer it, from its set or every set if it's a
+// broadcast. Responses are collected until the deadline if fewer arrive,
+// which is only an error when none do.
+//
+// Commands go out one at a time in the order they were queued, each
+// waiting for its acknowledgement and Gap before the next is written. A
+// caller that gives up still holds its place until its read finishes, so
+// a cancelled command never steals a later command's reply.
+func (s *Serial) transact(ctx context.Context, frame []byte, n int) ([]byte, error) {
+	s.mu.Lock()
+	open := s.conn != nil
+	s.mu.Unlock()
+	if !open {
+		return nil, errNotOpen
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.run()
+	j := &job{ctx: ctx, frame: frame, n: n, res: make(chan ack, 1)}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.quit:
+		return nil, ErrPortClosed
+	case s.jobs <- j:
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case a := <-j.res:
+		return a.resp, a.err
+	}
+}
+
+// run starts the goroutine that owns the port, once.
+func (s *Serial) run() {
+	s.start.Do(func() {
+		s.jobs = make(chan *job)
+		s.quit = make(chan struct{})
+		go s.work()
+	})
+}
+
+// work runs queued jobs until Close. While a failed port is being
+// reopened, jobs fail straight away so no caller waits on a dead device.
+func (s *Serial) work() {
+	var (
+		down  bool
+		retry <-chan time.Time
+		wait  = s.reconnectMin()
+	)
+
+	for {
+		select {
+		case <-s.quit:
+			return
+
+		case <-retry:
+			if err := s.reopen(); err != nil {
+				if wait *= 2; wait > s.reconnectMax() {
+					wait = s.reconnectMax()
+				}
+				retry = time.After(wait)
+				continue
+			}
+			down, retry, wait = false, nil, s.reconnectMin()
+
+		case j := <-s.jobs:
+			select {
+			case <-s.quit:
+				j.res <- ack{err: ErrPortClosed}
+				return
+			default:
+			}
+
+			if down {
+				j.res <- ack{err: ErrPortClosed}
+				continue
+			}
+
+			a := s.do(j)
+			j.res <- a
+			if s.Reconnect && errors.Is(a.err, ErrPortClosed) {
+				s.conn.Close()
+				down, retry = true, time.After(wait)
+			}
+		}
+	}
+}
+
+// reopen replaces a failed port, unless Close got there first.
+func (s *Serial) reopen() error {
+	c, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.quit:
+		c.Close()
+	default:
+		s.conn = c
+	}
+	return nil
+}
+
+func (s *Serial) reconnectMin() time.Duration {
+	if s.ReconnectMin == 0 {
+		return 100 * time.Millisecond
+	}
+	return s.ReconnectMin
+}
+
+func (s *Serial) reconnectMax() time.Duration {
+	if s.ReconnectMax == 0 {
+		return 10 * time.Second
+	}
+	return s.ReconnectMax
+}
+
+func (s *Serial) readTimeout() time.Duration {
+	if s.ReadTimeout == 0 {
+		return time.Second
+	}
+	return s.ReadTimeout
+}
+
+// do writes a job's frame, once the gap since the last command has passed,
+// and reads its acknowledgements.
+func (s *Serial) do(j *job) ack {
+	if wait := time.Until(s.last.Add(s.Gap)); wait > 0 {
+		t := time.NewTimer(wait)
+		select {
+		case <-j.ctx.Done():
+			t.Stop()
+			return ack{err: j.ctx.Err()}
+		case <-t.C:
+		}
+	}
+
+	// Skip commands whose caller gave up while they were queued
+	if err := j.ctx.Err(); err != nil {
+		return ack{err: err}
+	}
+
+	deadline := time.Now().Add(s.readTimeout())
+	d, bound := j.ctx.Deadline()
+	if bound = bound && d.Before(deadline); bound {
+		deadline = d
+	}
+
+	if err := s.drain(); err != nil {
+		return ack{err: portErr(err)}
+	}
+	if _, err := s.conn.Write(j.frame); err != nil {
+		return ack{err: portErr(err)}
+	}
+	defer func() { s.last = time.Now() }()
+
+	resp, err := s.readAcks(deadline, j.frame, j.n)
+	switch {
+	case err == ErrTimeout && bound:
+		err = context.DeadlineExceeded
+	case err != nil && err != ErrTimeout:
+		err = portErr(err)
+	}
+	return ack{resp: resp, err: err}
+}
+
+// portErr reports a failed read or write as ErrPortClosed, since the port
+// is no use after one.
+func portErr(err error) error {
+	return fmt.Errorf("%w: %v", ErrPortClosed, err)
+}
@@ -0,0 +1,296 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// strictPort is an emuPort that counts frames written before the previous
+// reply was read, which only happens if commands interleave.
+type strictPort struct {
+	emuPort
+	overlaps int
+}
+
+func (p *strictPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	if p.rx.Len() > 0 {
+		p.overlaps++
+	}
+	p.mu.Unlock()
+	return p.emuPort.Write(b)
+}
+
+// latePort is a fakePort that holds back its reply to each frame by the
+// next of delays, replying at once when they run out.
+type latePort struct {
+	fakePort
+	delays []time.Duration
+}
+
+func (p *latePort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	p.tx = append(p.tx, string(b))
+
+	var d time.Duration
+	if len(p.delays) > 0 {
+		d, p.delays = p.delays[0], p.delays[1:]
+	}
+	reply := p.replies[string(b)]
+	time.AfterFunc(d, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.rx.WriteString(reply)
+	})
+	return len(b), nil
+}
+
+func TestQueue(t *testing.T) {
+	Convey("Testing the command queue", t, func() {
+		Convey("running test: Concurrent callers get their own acks", func() {
+			ids := make([]int, 20)
+			for i := range ids {
+				ids[i] = i + 1
+			}
+			p := &strictPort{emuPort: emuPort{e: NewSerialEmulator(ids...)}}
+			s := &Serial{MaxID: len(ids), ReadTimeout: time.Second, conn: p}
+			defer s.Close()
+
+			var (
+				wg   sync.WaitGroup
+				mu   sync.Mutex
+				got  = make(map[int]int)
+				errs []error
+			)
+			for _, id := range ids {
+				wg.Add(1)
+				go func(id int) {
+					defer wg.Done()
+					err := s.SetVolume(context.Background(), id, id)
+					r, qerr := s.Query(context.Background(), id, "VolLvl")
+					mu.Lock()
+					defer mu.Unlock()
+					if err == nil {
+						err = qerr
+					}
+					if err != nil {
+						errs = append(errs, err)
+					}
+					got[id] = r.Value
+				}(id)
+			}
+			wg.Wait()
+
+			So(errs, ShouldBeEmpty)
+			for _, id := range ids {
+				So(got[id], ShouldEqual, id)
+			}
+			So(p.tx, ShouldHaveLength, 2*len(ids))
+			So(p.overlaps, ShouldEqual, 0)
+		})
+
+		Convey("running test: Gap paces commands", func() {
+			s := &Serial{Gap: 60 * time.Millisecond, ID: 1, ReadTimeout: time.Second, conn: &emuPort{e: NewSerialEmulator()}}
+			defer s.Close()
+
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				So(s.Do(context.Background(), "MuteOn"), ShouldBeNil)
+			}
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 120*time.Millisecond)
+		})
+
+		Convey("running test: Default ReadTimeout", func() {
+			s := &Serial{ID: 1, conn: &emuPort{e: NewSerialEmulator()}}
+			defer s.Close()
+
+			So(s.Do(context.Background(), "MuteOn"), ShouldBeNil)
+		})
+
+		Convey("running test: Cancelled while queued", func() {
+			p := &fakePort{replies: map[string]string{"ka 01 01\r": "a 01 OK01x"}}
+			s := &Serial{ReadTimeout: 200 * time.Millisecond, conn: p}
+			defer s.Close()
+
+			// Set 2 is silent, so its read holds the port until ReadTimeout
+			done := make(chan error)
+			go func() {
+				_, err := s.Xmit(context.Background(), 2, "PowerOn")
+				done <- err
+			}()
+			time.Sleep(20 * time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := s.Xmit(ctx, 1, "PowerOn")
+			So(err, ShouldResemble, context.DeadlineExceeded)
+
+			So(<-done, ShouldEqual, ErrTimeout)
+			ok, err := s.Xmit(context.Background(), 1, "PowerOn")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(p.tx, ShouldResemble, []string{"ka 02 01\r", "ka 01 01\r"})
+		})
+
+		Convey("running test: Stale ack discarded", func() {
+			p := &fakePort{replies: map[string]string{
+				"kf 01 1E\r": "f 01 OK1Ex",
+				"ka 01 FF\r": "a 01 OK01x",
+			}}
+			p.rx.WriteString("a 01 OK01x")
+			s := &Serial{ReadTimeout: 50 * time.Millisecond, conn: p}
+			defer s.Close()
+
+			So(s.SetVolume(context.Background(), 1, 30), ShouldBeNil)
+			r, err := s.Query(context.Background(), 1, "PowerStatus")
+			So(err, ShouldBeNil)
+			So(r.String(), ShouldEqual, "PowerOn")
+		})
+
+		Convey("running test: Late ack after a retry", func() {
+			// MuteOn's first ack comes after the retry, and while the
+			// volume query waits for its own
+			p := &latePort{
+				fakePort: fakePort{replies: map[string]string{
+					"ke 01 00\r": "e 01 OK00x",
+					"kf 01 FF\r": "f 01 OK14x",
+				}},
+				delays: []time.Duration{300 * time.Millisecond, 0, 150 * time.Millisecond},
+			}
+			s := &Serial{ID: 1, ReadTimeout: 200 * time.Millisecond, Retry: RetryPolicy{Attempts: 2}, conn: p}
+			defer s.Close()
+
+			So(s.Do(context.Background(), "MuteOn"), ShouldBeNil)
+			r, err := s.Query(context.Background(), 1, "VolLvl")
+			So(err, ShouldBeNil)
+			So(r.Value, ShouldEqual, 20)
+			So(p.tx, ShouldResemble, []string{"ke 01 00\r", "ke 01 00\r", "kf 01 FF\r"})
+		})
+
+		Convey("running test: Close", func() {
+			p := &fakePort{}
+			s := &Serial{ReadTimeout: time.Second, conn: p}
+
+			So(s.Close(), ShouldBeNil)
+			So(p.closed, ShouldBeTrue)
+			_, err := s.Xmit(context.Background(), 1, "PowerOn")
+			So(err, ShouldEqual, ErrPortClosed)
+			So(s.Close(), ShouldBeNil)
+		})
+	})
+}
+
+// fakeDevice opens fakePorts that answer MuteOn, unless it's unplugged.
+type fakeDevice struct {
+	mu        sync.Mutex
+	ports     []*fakePort
+	unplugged bool
+}
+
+func (d *fakeDevice) open() (io.ReadWriteCloser, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.unplugged {
+		return nil, errors.New("no such device")
+	}
+	p := &fakePort{replies: map[string]string{"ke 01 00\r": "e 01 OK00x"}}
+	d.ports = append(d.ports, p)
+	return p, nil
+}
+
+// unplug fails the open port and every open until plug.
+func (d *fakeDevice) unplug() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.unplugged = true
+	d.ports[len(d.ports)-1].Close()
+}
+
+func (d *fakeDevice) plug() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.unplugged = false
+}
+
+func (d *fakeDevice) opened() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.ports)
+}
+
+func TestReconnect(t *testing.T) {
+	Convey("Testing Serial reconnection", t, func() {
+		ctx := context.Background()
+		d := &fakeDevice{}
+		s := &Serial{
+			ID:           1,
+			Opener:       d.open,
+			ReadTimeout:  100 * time.Millisecond,
+			ReconnectMin: 10 * time.Millisecond,
+			ReconnectMax: 40 * time.Millisecond,
+		}
+
+		Convey("running test: Reopens once the device is back", func() {
+			s.Reconnect = true
+			So(s.Open(), ShouldBeNil)
+			defer s.Close()
+			So(s.Do(ctx, "MuteOn"), ShouldBeNil)
+
+			d.unplug()
+			err := s.Do(ctx, "MuteOn")
+			So(errors.Is(err, ErrPortClosed), ShouldBeTrue)
+
+			// Commands fail fast while the device is gone
+			start := time.Now()
+			err = s.Do(ctx, "MuteOn")
+			So(err, ShouldEqual, ErrPortClosed)
+			So(time.Since(start), ShouldBeLessThan, s.ReadTimeout)
+
+			time.Sleep(100 * time.Millisecond)
+			So(d.opened(), ShouldEqual, 1)
+			d.plug()
+
+			deadline := time.Now().Add(time.Second)
+			for err != nil && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+				err = s.Do(ctx, "MuteOn")
+			}
+			So(err, ShouldBeNil)
+			So(d.opened(), ShouldEqual, 2)
+			So(d.ports[1].tx, ShouldResemble, []string{"ke 01 00\r"})
+
+			So(s.Close(), ShouldBeNil)
+			So(d.ports[1].closed, ShouldBeTrue)
+			So(s.Do(ctx, "MuteOn"), ShouldEqual, ErrPortClosed)
+		})
+
+		Convey("running test: Stays closed without Reconnect", func() {
+			So(s.Open(), ShouldBeNil)
+			defer s.Close()
+
+			d.unplug()
+			d.plug()
+			time.Sleep(50 * time.Millisecond)
+
+			err := s.Do(ctx, "MuteOn")
+			So(errors.Is(err, ErrPortClosed), ShouldBeTrue)
+			So(d.opened(), ShouldEqual, 1)
+		})
+
+		Convey("running test: Open fails while unplugged", func() {
+			d.unplugged = true
+			So(s.Open(), ShouldNotBeNil)
+			So(s.Do(ctx, "MuteOn"), ShouldEqual, errNotOpen)
+		})
+	})
+}
@@ -0,0 +1,243 @@
+package lgtv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Device is a TV set the registry knows, reached by network, serial or
+// both.
+type Device struct {
+	Name  string `json:"name"`
+	Model string `json:"model,omitempty"`
+
+	// Network identity and pairing, learnt by discovery
+	IP        net.IP `json:"ip,omitempty"`
+	MAC       string `json:"mac,omitempty"`
+	Protocol  string `json:"protocol,omitempty"`
+	Pin       string `json:"pin,omitempty"`
+	ClientKey string `json:"client_key,omitempty"`
+
+	// Serial port and set ID, for a set wired to RS-232C
+	Port  string `json:"port,omitempty"`
+	SetID int    `json:"set_id,omitempty"`
+
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// DeviceList is a set of TV sets from the registry.
+type DeviceList []Device
+
+func (l DeviceList) String() string {
+	b, _ := json.MarshalIndent(l, "", "\t")
+	return string(b)
+}
+
+// Registry is a JSON file of known TV sets, keyed by friendly name. Every
+// access locks the file, so concurrent processes can share it.
+type Registry struct {
+	Path string
+}
+
+// registryFile is the registry's layout on disk.
+type registryFile struct {
+	Devices []Device `json:"devices"`
+}
+
+// DefaultRegistryPath returns where the registry lives unless told
+// otherwise, e.g. ~/.config/lgtv/tvs.json on Linux.
+func DefaultRegistryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "lgtv", "tvs.json")
+}
+
+// Devices returns every TV set in the registry, sorted by name.
+func (r *Registry) Devices() (DeviceList, error) {
+	var devs DeviceList
+	err := r.access(func(m map[string]*Device) error {
+		for _, d := range m {
+			devs = append(devs, *d)
+		}
+		return nil
+	}, false)
+	sort.Slice(devs, func(i, j int) bool { return devs[i].Name < devs[j].Name })
+	return devs, err
+}
+
+// Lookup returns the TV set called name.
+func (r *Registry) Lookup(name string) (Device, error) {
+	var d Device
+	err := r.access(func(m map[string]*Device) error {
+		dev, ok := m[name]
+		if !ok {
+			return fmt.Errorf("no TV called %q in %s", name, r.Path)
+		}
+		d = *dev
+		return nil
+	}, false)
+	return d, err
+}
+
+// Put adds d to the registry, replacing any TV set of the same name.
+func (r *Registry) Put(d Device) error {
+	return r.Update(func(m map[string]*Device) error {
+		m[d.Name] = &d
+		return nil
+	})
+}
+
+// Rename gives the TV set called old a new name.
+func (r *Registry) Rename(old, name string) error {
+	return r.Update(func(m map[string]*Device) error {
+		d, ok := m[old]
+		switch {
+		case !ok:
+			return fmt.Errorf("no TV called %q in %s", old, r.Path)
+		case m[name] != nil:
+			return fmt.Errorf("%q is already in %s", name, r.Path)
+		}
+		delete(m, old)
+		d.Name = name
+		m[name] = d
+		return nil
+	})
+}
+
+// Record merges the TVs found by Discover at time seen. A TV is matched to
+// an entry by its IP address; new ones are named after their model.
+func (r *Registry) Record(tvs TVList, seen time.Time) error {
+	// UDAP answers go first, so a TV that answers both is named after the
+	// model UDAP gives rather than SSDP's SERVER header
+	tvs = append(TVList(nil), tvs...)
+	sort.SliceStable(tvs, func(i, j int) bool {
+		return tvs[i].Protocol == ProtoUDAP && tvs[j].Protocol != ProtoUDAP
+	})
+
+	return r.Update(func(m map[string]*Device) error {
+		byIP := make(map[string]*Device)
+		for _, d := range m {
+			if d.IP != nil {
+				byIP[d.IP.String()] = d
+			}
+		}
+
+		for _, tv := range tvs {
+			d, ok := byIP[tv.IP.String()]
+			if !ok {
+				d = &Device{Name: freeName(m, tv.Name, tv.IP), IP: tv.IP}
+				m[d.Name] = d
+				byIP[tv.IP.String()] = d
+			}
+
+			// SSDP's SERVER header usually ends in a UPnP version, so it
+			// only stands in for the model until UDAP names it
+			if tv.Name != "" && (d.Model == "" || tv.Protocol == ProtoUDAP) {
+				d.Model = tv.Name
+			}
+			if d.Protocol == "" || tv.Protocol == ProtoUDAP {
+				d.Protocol = tv.Protocol
+			}
+			if tv.MAC != "" {
+				d.MAC = tv.MAC
+			}
+			d.LastSeen = seen
+		}
+		return nil
+	})
+}
+
+// freeName returns a name for a new TV set that isn't taken in m.
+func freeName(m map[string]*Device, model string, ip net.IP) string {
+	base := model
+	if base == "" {
+		base = ip.String()
+	}
+
+	name := base
+	for i := 2; m[name] != nil; i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	return name
+}
+
+// Update locks the registry, passes its TV sets to f keyed by name and
+// writes them back unless f fails. A missing file is an empty registry.
+func (r *Registry) Update(f func(map[string]*Device) error) error {
+	return r.access(f, true)
+}
+
+// access passes the registry's TV sets to f under the lock, writing them
+// back afterwards if write is set.
+func (r *Registry) access(f func(map[string]*Device) error, write bool) error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(r.Path + ".lock")
+	if err != nil {
+		return fmt.Errorf("locking %s: %v", r.Path, err)
+	}
+	defer unlock()
+
+	var rf registryFile
+	b, err := ioutil.ReadFile(r.Path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(b, &rf); err != nil {
+			return fmt.Errorf("%s: %v", r.Path, err)
+		}
+	}
+
+	m := make(map[string]*Device, len(rf.Devices))
+	for i := range rf.Devices {
+		m[rf.Devices[i].Name] = &rf.Devices[i]
+	}
+
+	if err := f(m); err != nil {
+		return err
+	}
+
+	if !write {
+		return nil
+	}
+
+	devs := make([]Device, 0, len(m))
+	for _, d := range m {
+		devs = append(devs, *d)
+	}
+	sort.Slice(devs, func(i, j int) bool { return devs[i].Name < devs[j].Name })
+
+	out, err := json.MarshalIndent(registryFile{Devices: devs}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	// Replace the file whole, so a crash never leaves half a registry
+	tmp, err := ioutil.TempFile(filepath.Dir(r.Path), filepath.Base(r.Path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(out, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), r.Path)
+}
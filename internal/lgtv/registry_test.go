@@ -0,0 +1,144 @@
+package lgtv
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistry(t *testing.T) {
+	seen := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+	later := seen.Add(time.Hour)
+
+	Convey("Testing Registry", t, func() {
+		r := &Registry{Path: filepath.Join(t.TempDir(), "lgtv", "tvs.json")}
+
+		Convey("running test: Empty registry", func() {
+			devs, err := r.Devices()
+			So(err, ShouldBeNil)
+			So(devs, ShouldBeEmpty)
+
+			_, err = r.Lookup("lobby")
+			So(err.Error(), ShouldEqual, `no TV called "lobby" in `+r.Path)
+		})
+
+		Convey("running test: Put, Lookup and Rename", func() {
+			So(r.Put(Device{Name: "boardroom", Port: "serial:A6008isP", SetID: 2}), ShouldBeNil)
+
+			d, err := r.Lookup("boardroom")
+			So(err, ShouldBeNil)
+			So(d, ShouldResemble, Device{Name: "boardroom", Port: "serial:A6008isP", SetID: 2})
+
+			So(r.Put(Device{Name: "lobby"}), ShouldBeNil)
+			So(r.Rename("boardroom", "lobby").Error(), ShouldEqual, `"lobby" is already in `+r.Path)
+			So(r.Rename("kitchen", "den").Error(), ShouldEqual, `no TV called "kitchen" in `+r.Path)
+			So(r.Rename("boardroom", "boardroom 2"), ShouldBeNil)
+
+			devs, err := r.Devices()
+			So(err, ShouldBeNil)
+			So(devs, ShouldHaveLength, 2)
+			So(devs[0].Name, ShouldEqual, "boardroom 2")
+			So(devs[0].SetID, ShouldEqual, 2)
+			So(devs[1].Name, ShouldEqual, "lobby")
+		})
+
+		Convey("running test: Record discovery results", func() {
+			a, b := net.IPv4(192, 168, 1, 20), net.IPv4(192, 168, 1, 21)
+			So(r.Record(TVList{
+				{IP: a, Name: "webOSTV/1.0", Protocol: ProtoSSDP},
+				{IP: a, Name: "42LW5700", Protocol: ProtoUDAP, MAC: "a8:23:fe:01:02:03"},
+				{IP: b, Name: "42LW5700", Protocol: ProtoUDAP},
+			}, seen), ShouldBeNil)
+
+			devs, err := r.Devices()
+			So(err, ShouldBeNil)
+			So(devs, ShouldResemble, DeviceList{
+				{Name: "42LW5700", Model: "42LW5700", IP: a, MAC: "a8:23:fe:01:02:03", Protocol: ProtoUDAP, LastSeen: seen},
+				{Name: "42LW5700-2", Model: "42LW5700", IP: b, Protocol: ProtoUDAP, LastSeen: seen},
+			})
+
+			// A renamed, paired TV keeps its name and PIN when seen again
+			So(r.Rename("42LW5700", "lobby"), ShouldBeNil)
+			So(r.Update(func(m map[string]*Device) error {
+				m["lobby"].Pin = "123456"
+				return nil
+			}), ShouldBeNil)
+			So(r.Record(TVList{{IP: a, Name: "webOSTV/1.0", Protocol: ProtoSSDP}}, later), ShouldBeNil)
+
+			d, err := r.Lookup("lobby")
+			So(err, ShouldBeNil)
+			So(d, ShouldResemble, Device{Name: "lobby", Model: "42LW5700", IP: a, MAC: "a8:23:fe:01:02:03", Protocol: ProtoUDAP, Pin: "123456", LastSeen: later})
+		})
+
+		Convey("running test: UDAP's model wins over SSDP's", func() {
+			ip := net.IPv4(192, 168, 1, 20)
+			udap := TVInfo{IP: ip, Name: "42LW5700", Protocol: ProtoUDAP}
+			ssdp := TVInfo{IP: ip, Name: "webOSTV/1.0", Protocol: ProtoSSDP}
+			want := DeviceList{{Name: "42LW5700", Model: "42LW5700", IP: ip, Protocol: ProtoUDAP, LastSeen: seen}}
+
+			tests := []struct {
+				name   string
+				finds  []TVList
+				device DeviceList
+			}{
+				{name: "UDAP then SSDP", finds: []TVList{{udap, ssdp}}, device: want},
+				{name: "SSDP then UDAP", finds: []TVList{{ssdp, udap}}, device: want},
+				{name: "UDAP, then SSDP later", finds: []TVList{{udap}, {ssdp}}, device: want},
+				{
+					name:  "SSDP, then UDAP later",
+					finds: []TVList{{ssdp}, {udap}},
+					device: DeviceList{
+						{Name: "webOSTV/1.0", Model: "42LW5700", IP: ip, Protocol: ProtoUDAP, LastSeen: seen},
+					},
+				},
+			}
+
+			for _, tt := range tests {
+				Convey("running test: "+tt.name, func() {
+					for _, f := range tt.finds {
+						So(r.Record(f, seen), ShouldBeNil)
+					}
+					devs, err := r.Devices()
+					So(err, ShouldBeNil)
+					So(devs, ShouldResemble, tt.device)
+				})
+			}
+		})
+
+		Convey("running test: Corrupt file", func() {
+			So(r.Put(Device{Name: "lobby"}), ShouldBeNil)
+			So(ioutil.WriteFile(r.Path, []byte("{"), 0600), ShouldBeNil)
+
+			_, err := r.Devices()
+			So(err.Error(), ShouldStartWith, r.Path+": ")
+		})
+
+		Convey("running test: Concurrent updates", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					// A second Registry opens the file on its own, like another process
+					(&Registry{Path: r.Path}).Update(func(m map[string]*Device) error {
+						if m["counter"] == nil {
+							m["counter"] = &Device{Name: "counter"}
+						}
+						m["counter"].SetID++
+						return nil
+					})
+				}()
+			}
+			wg.Wait()
+
+			d, err := r.Lookup("counter")
+			So(err, ShouldBeNil)
+			So(d.SetID, ShouldEqual, 20)
+		})
+	})
+}
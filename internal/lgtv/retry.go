@@ -0,0 +1,55 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RetryPolicy says how often a failed command is sent again. The zero
+// value sends each command once.
+type RetryPolicy struct {
+	// Attempts is the most times a command is sent, once if zero.
+	Attempts int
+
+	// Backoff is the wait before the second attempt, doubling for each
+	// attempt after that.
+	Backoff time.Duration
+
+	// Retryable reports whether a failed attempt is worth repeating. If
+	// nil, only timeouts are. ErrNG is never retried: the set heard the
+	// command and refused it.
+	Retryable func(error) bool
+}
+
+// retryable reports whether err is worth another attempt.
+func (p RetryPolicy) retryable(err error) bool {
+	switch {
+	case errors.Is(err, ErrNG):
+		return false
+	case p.Retryable != nil:
+		return p.Retryable(err)
+	}
+	return errors.Is(err, ErrTimeout)
+}
+
+// do calls f until it succeeds, fails for good, ctx is done or the
+// attempts run out, and returns f's last error.
+func (p RetryPolicy) do(ctx context.Context, f func() error) error {
+	wait := p.Backoff
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || i >= p.Attempts || !p.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
@@ -0,0 +1,173 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	logging "github.com/op/go-logging"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// dropPort is a fakePort that ignores the first drop frames written to it.
+type dropPort struct {
+	fakePort
+	drop int
+}
+
+func (p *dropPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	if p.drop > 0 {
+		p.drop--
+		p.tx = append(p.tx, string(b))
+		p.mu.Unlock()
+		return len(b), nil
+	}
+	p.mu.Unlock()
+	return p.fakePort.Write(b)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("Testing RetryPolicy", t, func() {
+		badFrame := &FrameError{Frame: "zzx", Reason: "no acknowledgement for MuteOn"}
+		tests := []struct {
+			name  string
+			p     RetryPolicy
+			err   error
+			tries int
+		}{
+			{name: "Zero value sends once", err: ErrTimeout, tries: 1},
+			{name: "Timeouts retried", p: RetryPolicy{Attempts: 3}, err: ErrTimeout, tries: 3},
+			{name: "Caller deadline not retried", p: RetryPolicy{Attempts: 2}, err: context.DeadlineExceeded, tries: 1},
+			{name: "NG never retried", p: RetryPolicy{Attempts: 3}, err: ErrNG, tries: 1},
+			{
+				name:  "NG never retried, whatever Retryable says",
+				p:     RetryPolicy{Attempts: 3, Retryable: func(error) bool { return true }},
+				err:   ErrNG,
+				tries: 1,
+			},
+			{name: "Bad frames not retried by default", p: RetryPolicy{Attempts: 3}, err: badFrame, tries: 1},
+			{
+				name:  "Bad frames retried on request",
+				p:     RetryPolicy{Attempts: 3, Retryable: func(err error) bool { return errors.Is(err, ErrBadFrame) }},
+				err:   badFrame,
+				tries: 3,
+			},
+			{name: "Success", p: RetryPolicy{Attempts: 3}, tries: 1},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				var tries int
+				err := tt.p.do(context.Background(), func() error {
+					tries++
+					return tt.err
+				})
+				So(err, ShouldResemble, tt.err)
+				So(tries, ShouldEqual, tt.tries)
+			})
+		}
+
+		Convey("running test: Backoff doubles", func() {
+			var at []time.Time
+			p := RetryPolicy{Attempts: 3, Backoff: 20 * time.Millisecond}
+			p.do(context.Background(), func() error {
+				at = append(at, time.Now())
+				return ErrTimeout
+			})
+			So(at, ShouldHaveLength, 3)
+			So(at[1].Sub(at[0]), ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+			So(at[2].Sub(at[1]), ShouldBeGreaterThanOrEqualTo, 40*time.Millisecond)
+		})
+
+		Convey("running test: Cancelled during backoff", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			var tries int
+			p := RetryPolicy{Attempts: 3, Backoff: time.Second}
+			err := p.do(ctx, func() error {
+				tries++
+				return ErrTimeout
+			})
+			So(err, ShouldEqual, ErrTimeout)
+			So(tries, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestSerialErrors(t *testing.T) {
+	Convey("Testing Serial errors and retries", t, func() {
+		const xmit = "ke 01 00\r"
+		tests := []struct {
+			name    string
+			reply   string
+			drop    int
+			closed  bool
+			retry   RetryPolicy
+			wantErr error
+			tx      int
+		}{
+			{name: "OK", reply: "e 01 OK00x", tx: 1},
+			{name: "NG", reply: "e 01 NG00x", retry: RetryPolicy{Attempts: 3}, wantErr: ErrNG, tx: 1},
+			{name: "Timeout", drop: 1, wantErr: ErrTimeout, tx: 1},
+			{name: "Timeout retried", reply: "e 01 OK00x", drop: 2, retry: RetryPolicy{Attempts: 3}, tx: 3},
+			{name: "Retries run out", reply: "e 01 OK00x", drop: 2, retry: RetryPolicy{Attempts: 2}, wantErr: ErrTimeout, tx: 2},
+			{name: "Garbled reply", reply: "a 01 OK00x", wantErr: ErrBadFrame, tx: 1},
+			{name: "Port closed", closed: true, retry: RetryPolicy{Attempts: 3}, wantErr: ErrPortClosed},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				p := &dropPort{fakePort: fakePort{replies: map[string]string{xmit: tt.reply}, closed: tt.closed}, drop: tt.drop}
+				s := &Serial{ID: 1, ReadTimeout: 30 * time.Millisecond, Retry: tt.retry, conn: p}
+				defer s.Close()
+
+				err := s.Do(context.Background(), "MuteOn")
+				if tt.wantErr == nil {
+					So(err, ShouldBeNil)
+				} else {
+					So(errors.Is(err, tt.wantErr), ShouldBeTrue)
+				}
+				So(p.tx, ShouldHaveLength, tt.tx)
+			})
+		}
+	})
+}
+
+func TestWebOSErrors(t *testing.T) {
+	Convey("Testing WebOS timeouts and retries", t, func() {
+		tests := []struct {
+			name     string
+			timeout  time.Duration
+			deadline time.Duration
+			retry    RetryPolicy
+			tries    int
+		}{
+			{name: "Timeout", timeout: 50 * time.Millisecond, tries: 1},
+			{name: "Timeout retried", timeout: 50 * time.Millisecond, retry: RetryPolicy{Attempts: 3}, tries: 3},
+			{name: "Caller deadline", timeout: 10 * time.Second, deadline: 100 * time.Millisecond, retry: RetryPolicy{Attempts: 3}, tries: 1},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				port, accepted := hungTV(t)
+				w := &WebOS{Logger: logging.MustGetLogger("test"), IP: net.IPv4(127, 0, 0, 1), Port: port, Retry: tt.retry, Timeout: tt.timeout}
+				defer w.Close()
+
+				ctx := context.Background()
+				if tt.deadline > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+					defer cancel()
+				}
+
+				err := w.Do(ctx, "Home")
+				So(errors.Is(err, ErrTimeout), ShouldBeTrue)
+				So(accepted(), ShouldEqual, tt.tries)
+			})
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package lgtv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// SetInfo describes a TV set that answered a Scan.
+type SetInfo struct {
+	ID    int    `json:"id"`
+	Power string `json:"power"`
+	Lamp  string `json:"lamp,omitempty"`
+	Hours int    `json:"hours"`
+}
+
+// ScanReport lists which set IDs on a daisy chain answered a Scan.
+type ScanReport struct {
+	Found  []SetInfo `json:"found"`
+	NG     []int     `json:"ng,omitempty"`
+	Silent []int     `json:"silent,omitempty"`
+}
+
+// Scan probes set IDs 1 to MaxID with PowerStatus and reads the lamp
+// status and elapsed hours of each set that answers. Every silent ID costs
+// ReadTimeout, so keep MaxID close to the size of the chain. Scan stops if
+// the port closes or fails rather than report the rest as Silent.
+func (s *Serial) Scan(ctx context.Context) (ScanReport, error) {
+	var rep ScanReport
+
+	for id := 1; id <= s.maxID(); id++ {
+		r, err := s.Query(ctx, id, "PowerStatus")
+		switch {
+		case err == nil:
+		case err == errNotOpen || errors.Is(err, ErrPortClosed) || ctx.Err() != nil:
+			return rep, err
+		case errors.Is(err, ErrNG):
+			rep.NG = append(rep.NG, id)
+			continue
+		default:
+			rep.Silent = append(rep.Silent, id)
+			continue
+		}
+
+		info := SetInfo{ID: id, Power: r.String()}
+		if r, err := s.Query(ctx, id, "LampCheck"); err == nil {
+			info.Lamp = r.String()
+		}
+		if r, err := s.Query(ctx, id, "TimeElapsed"); err == nil {
+			info.Hours = r.Value
+		}
+		rep.Found = append(rep.Found, info)
+	}
+
+	return rep, nil
+}
+
+func (r ScanReport) String() string {
+	b, _ := json.MarshalIndent(r, "", "\t")
+	return string(b)
+}
@@ -0,0 +1,74 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScan(t *testing.T) {
+	Convey("Testing Scan()", t, func() {
+		s := &Serial{
+			MaxID:       4,
+			ReadTimeout: 50 * time.Millisecond,
+			conn: &fakePort{replies: map[string]string{
+				"ka 01 FF\r": "a 01 OK01x",
+				"dp 01 FF\r": "p 01 OK01x",
+				"dl 01 FF\r": "l 01 OK04D2x",
+				"ka 02 FF\r": "a 02 NG00x",
+				"ka 04 FF\r": "a 04 OK00x",
+				"dp 04 FF\r": "p 04 NG00x",
+				"dl 04 FF\r": "l 04 OK0010x",
+			}},
+		}
+
+		Convey("running test: Mixed chain", func() {
+			got, err := s.Scan(context.Background())
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, ScanReport{
+				Found: []SetInfo{
+					{ID: 1, Power: "PowerOn", Lamp: "LampOk", Hours: 1234},
+					{ID: 4, Power: "PowerOff", Hours: 16},
+				},
+				NG:     []int{2},
+				Silent: []int{3},
+			})
+		})
+
+		Convey("running test: Cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := s.Scan(ctx)
+			So(err, ShouldEqual, context.Canceled)
+		})
+
+		Convey("running test: Port closed", func() {
+			s.conn.Close()
+			got, err := s.Scan(context.Background())
+			So(errors.Is(err, ErrPortClosed), ShouldBeTrue)
+			So(got.Silent, ShouldBeEmpty)
+		})
+
+		Convey("running test: Emulated chain", func() {
+			e := NewSerialEmulator(2, 3)
+			path, err := e.ListenPTY()
+			if err != nil {
+				SkipSo(err, ShouldBeNil)
+				return
+			}
+			defer e.Close()
+
+			s := &Serial{Baud: 9600, MaxID: 3, Port: path, ReadTimeout: 200 * time.Millisecond}
+			So(s.Open(), ShouldBeNil)
+			defer s.Close()
+
+			got, err := s.Scan(context.Background())
+			So(err, ShouldBeNil)
+			So(len(got.Found), ShouldEqual, 2)
+			So(got.Silent, ShouldResemble, []int{1})
+		})
+	})
+}
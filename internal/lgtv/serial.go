@@ -1,34 +1,98 @@
 package lgtv
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/tarm/serial"
 )
 
-// MaxTVs sets how many TV sets are in use
+// MaxTVs sets how many TV sets are in use, with set IDs 1 to MaxTVs, unless
+// Serial.MaxID says otherwise
 var MaxTVs = 5
 
+const (
+	// BroadcastID addresses every TV set on a daisy chain at once.
+	BroadcastID = 0
+
+	// MaxSetID is the highest set ID the LG protocol allows.
+	MaxSetID = 99
+)
+
+// Errors reported by Serial, tested for with errors.Is.
+var (
+	// ErrNG means the TV set received the command and refused it.
+	ErrNG = errors.New("TV set answered NG")
+
+	// ErrTimeout means no acknowledgement arrived within ReadTimeout.
+	ErrTimeout = errors.New("timed out waiting for acknowledgement")
+
+	// ErrBadFrame means the reply couldn't be decoded; see FrameError.
+	ErrBadFrame = errors.New("malformed frame")
+
+	// ErrPortClosed means the port was closed or failed before the
+	// command was acknowledged.
+	ErrPortClosed = errors.New("serial port is closed")
+)
+
+var (
+	errNotOpen = errors.New("serial port is not open")
+
+	// pollInterval paces reads when the port returns no data
+	pollInterval = 10 * time.Millisecond
+)
+
 // Serializer implements Open and Xmit for LGTV serial control
 type Serializer interface {
-	Open() (*serial.Port, error)
+	Open() error
 	Xmit(ctx context.Context, id int, cmd string) (bool, error)
 }
 
 // Serial implements the Serializer interface
 type Serial struct {
-	Cmd            TVCmpMap
+	Cmd            TVCmpMap // precomputed frames for Xmit, encoded on demand if nil
+	ID             int      // TV set ID used by Do
 	Baud           int
-	Port           string
+	MaxID          int    // highest set ID on the chain, MaxTVs if zero
+	Port           string // device path, selector or network server; see ResolvePort and Open
 	Parity         serial.Parity
-	ReadTimeout    time.Duration
+	ReadTimeout    time.Duration // bounds the wait for acknowledgements, 1s if zero
 	RTSFlowControl bool
 	StopBits       serial.StopBits
 	XONFlowControl bool
+
+	// Retry repeats commands to a single set that fail; broadcasts are
+	// sent once.
+	Retry RetryPolicy
+
+	// Gap is the least time between one command's acknowledgement and
+	// the next command, for sets that drop commands sent too quickly.
+	Gap time.Duration
+
+	// Opener opens the port, the device named by Port if nil.
+	Opener func() (io.ReadWriteCloser, error)
+
+	// Reconnect closes the port after an I/O error and reopens it once
+	// the device is back, waiting ReconnectMin between tries and doubling
+	// up to ReconnectMax. Commands fail with ErrPortClosed meanwhile.
+	Reconnect    bool
+	ReconnectMin time.Duration // 100ms if zero
+	ReconnectMax time.Duration // 10s if zero
+
+	conn  io.ReadWriteCloser
+	mu    sync.Mutex
+	jobs  chan *job
+	quit  chan struct{}
+	start sync.Once
+	stop  sync.Once
+	last  time.Time
 }
 
 // CmdMode sets which API command is used
@@ -42,7 +106,7 @@ type LGCmd struct {
 	Cmd1 string `json:"1st cmd,omitempty"`
 	Cmd2 string `json:"2nd cmd,omitempty"`
 	Data string `json:"data,omitempty"`
-	Max  int    `json:"max,omitempty"`
+	Max  int    `json:"max,omitempty"` // ranged data bound, hexadecimal as in LG's manual
 	Note string `json:"note,omitempty"`
 	Web  int    `json:"WebOS,omitempty"`
 }
@@ -62,14 +126,67 @@ type XmitRes struct {
 // TVCmds is a map[string]LGCmd of RS-232C serial and WebOS commands.
 type TVCmds map[string]LGCmd
 
-func (r RespMap) respMapIDs() {
-	for i := 0; i < MaxTVs; i++ {
+// frame builds a serial command frame in LG's layout,
+// [Cmd1][Cmd2][ ][Set ID][ ][Data][Cr]
+func frame(cmd1, cmd2, id, data string) []byte {
+	return []byte(fmt.Sprintf("%s%s %s %s\r", cmd1, cmd2, id, data))
+}
+
+// splitFrames is a bufio.SplitFunc for command frames, which end in a
+// carriage return or line feed.
+func splitFrames(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// limit returns the upper bound of a ranged command's data. LG's manual
+// prints bounds in hexadecimal, so Max 64 means 0x64 (100).
+func (l LGCmd) limit() int {
+	v, _ := strconv.ParseUint(strconv.Itoa(l.Max), 16, 16)
+	return int(v)
+}
+
+// setID formats a TV set ID for a serial frame. IDs go on the wire in
+// hexadecimal, so set 10 is "0a".
+func setID(id int) string {
+	return fmt.Sprintf("%02x", id)
+}
+
+// parseSetID decodes a set ID from a frame, reporting false unless it's one
+// or two hexadecimal digits within 0..MaxSetID.
+func parseSetID(s string) (int, bool) {
+	if len(s) == 0 || len(s) > 2 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(s, 16, 8)
+	if err != nil || id > MaxSetID {
+		return 0, false
+	}
+	return int(id), true
+}
+
+func (r RespMap) respMapIDs(maxID int) {
+	for i := 0; i <= maxID; i++ {
 		r[i] = make(map[string]string)
 	}
 }
 
-// SetSerialCmds builds a set of serial commands
+// SetSerialCmds builds a set of serial commands for set IDs 0 to MaxTVs
+//
+// Deprecated: Xmit and Encode build frames on demand without the table.
 func (tv TVCmds) SetSerialCmds() TVCmpMap {
+	return tv.SerialCmdsFor(MaxTVs)
+}
+
+// SerialCmdsFor builds a set of serial commands for set IDs 0 to maxID
+//
+// Deprecated: Xmit and Encode build frames on demand without the table.
+func (tv TVCmds) SerialCmdsFor(maxID int) TVCmpMap {
 	ok := func(l LGCmd) bool {
 		if l.Data == "FF" || (l.Cmd1 == "" && l.Cmd2 == "") {
 			return false
@@ -77,40 +194,33 @@ func (tv TVCmds) SetSerialCmds() TVCmpMap {
 		return true
 	}
 
-	xmitres := func(cmd1, cmd2, id, data string) XmitRes {
-		x := XmitRes{
-			Resp: make(map[string][]byte),
-			Xmit: []byte(fmt.Sprintf("%s %s %v %s\n", cmd1, cmd2, id, data)),
+	// Resp holds the acknowledgements a set sends back, e.g. "a 01 OK01x"
+	xmitres := func(cmd1, cmd2 string, id int, data string) XmitRes {
+		ack := func(ok bool) []byte {
+			return []byte(Ack{Cmd2: cmd2, ID: id, OK: ok, Data: data}.frame())
 		}
-		for _, code := range []string{"NG", "OK"} {
-			x.Resp[code] = []byte(fmt.Sprintf("%s %s %s %s %sx", cmd1, cmd2, code, id, data))
+		return XmitRes{
+			Resp: map[string][]byte{"NG": ack(false), "OK": ack(true)},
+			Xmit: frame(cmd1, cmd2, setID(id), data),
 		}
-		return x
 	}
 
 	tvc := make(TVCmpMap)
-	for i := 0; i < MaxTVs; i++ {
+	for i := 0; i <= maxID; i++ {
 		tvc[i] = make(map[string]XmitRes)
 	}
 
 	for id := range tvc {
-		idStr := strconv.Itoa(id)
-		if id < 10 {
-			idStr = "0" + strconv.Itoa(id)
-		}
 		for tvKey := range tv {
 			v := tv[tvKey]
 			if ok(v) {
 				switch v.Max {
 				case 0:
-					tvc[id][tvKey+v.Data] = xmitres(v.Cmd1, v.Cmd2, idStr, v.Data)
+					tvc[id][tvKey+v.Data] = xmitres(v.Cmd1, v.Cmd2, id, v.Data)
 				default:
-					for i := 0; i <= v.Max; i++ {
-						data := strconv.Itoa(i)
-						if i < 10 {
-							data += "0"
-						}
-						tvc[id][tvKey+data] = xmitres(v.Cmd1, v.Cmd2, idStr, data)
+					for i := 0; i <= v.limit(); i++ {
+						data, _ := v.encode(tvKey, i)
+						tvc[id][tvKey+data] = xmitres(v.Cmd1, v.Cmd2, id, data)
 					}
 				}
 			}
@@ -120,8 +230,15 @@ func (tv TVCmds) SetSerialCmds() TVCmpMap {
 	return tvc
 }
 
-// GetRespMap creates a map of response keys mapped to LG TV functions.
+// GetRespMap creates a map of response keys mapped to LG TV functions for
+// set IDs 0 to MaxTVs.
 func (tv TVCmds) GetRespMap() RespMap {
+	return tv.RespMapFor(MaxTVs)
+}
+
+// RespMapFor creates a map of response keys mapped to LG TV functions for
+// set IDs 0 to maxID.
+func (tv TVCmds) RespMapFor(maxID int) RespMap {
 	ok := func(v LGCmd) bool {
 		if v.Data == "FF" || (v.Cmd1 == "" && v.Cmd2 == "") {
 			return false
@@ -130,12 +247,9 @@ func (tv TVCmds) GetRespMap() RespMap {
 	}
 
 	r := make(RespMap)
-	r.respMapIDs()
+	r.respMapIDs(maxID)
 	for id := range r {
-		idStr := strconv.Itoa(id)
-		if id < 10 {
-			idStr = "0" + strconv.Itoa(id)
-		}
+		idStr := setID(id)
 		for tvKey := range tv {
 			v := tv[tvKey]
 			if ok(v) {
@@ -144,12 +258,8 @@ func (tv TVCmds) GetRespMap() RespMap {
 					case 0:
 						r[id][fmt.Sprintf("%s %s %s %s %s x", v.Cmd1, v.Cmd2, code, idStr, v.Data)] = tvKey
 					default:
-						for i := 0; i <= v.Max; i++ {
-							data := strconv.Itoa(i)
-							if i < 10 {
-								data = "0" + data
-							}
-							r[id][fmt.Sprintf("%s %s %s %s %s x", v.Cmd1, v.Cmd2, code, idStr, data)] = tvKey
+						for i := 0; i <= v.limit(); i++ {
+							r[id][fmt.Sprintf("%s %s %s %s %02X x", v.Cmd1, v.Cmd2, code, idStr, i)] = tvKey
 						}
 					}
 				}
@@ -174,18 +284,327 @@ func (tv TVCmpMap) String() string {
 	return string(b)
 }
 
-// Open opens an asynchronous communications port.
-func (s *Serial) Open() (*serial.Port, error) {
-	return serial.OpenPort(
+// Open opens the port that Xmit and the other commands use. The port
+// belongs to s, which may reopen it; call Close when done.
+//
+// Besides local devices, Port may be "tcp://host:port" for a raw TCP
+// serial server such as ser2net, or "rfc2217://host:port" for one that
+// takes its baud rate and other line settings by RFC 2217.
+func (s *Serial) Open() error {
+	c, err := s.open()
+	if err != nil {
+		return err
+	}
+	s.setConn(c)
+	return nil
+}
+
+// Tap opens the port with s's line settings for a Monitor to listen on.
+// The port isn't used for commands and belongs to the caller.
+func (s *Serial) Tap() (io.ReadCloser, error) {
+	return s.open()
+}
+
+func (s *Serial) setConn(c io.ReadWriteCloser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn = c
+}
+
+// open opens the device Port selects, connects to the network serial
+// server it names, or calls Opener if set. Port is resolved afresh each
+// time, so a reconnect finds an adapter that came back under a new name.
+func (s *Serial) open() (io.ReadWriteCloser, error) {
+	if s.Opener != nil {
+		return s.Opener()
+	}
+	if isNetPort(s.Port) {
+		return s.dial()
+	}
+
+	name, err := ResolvePort(s.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := serial.OpenPort(
 		&serial.Config{
 			Baud:        s.Baud,
-			Name:        s.Port,
+			Name:        name,
 			Parity:      s.Parity,
 			ReadTimeout: s.ReadTimeout,
+			StopBits:    s.StopBits,
 		})
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("unsupported baud rate: %d", s.Baud)
+	}
+
+	// The serial package leaves flow control off
+	if s.RTSFlowControl || s.XONFlowControl {
+		if err := setFlowControl(name, s.RTSFlowControl, s.XONFlowControl); err != nil {
+			p.Close()
+			return nil, fmt.Errorf("flow control on %s: %v", name, err)
+		}
+	}
+	return p, nil
+}
+
+// Xmit sends cmd to TV set id and waits for the set's acknowledgement. It
+// reports true for an OK and false for an NG response. Sent to BroadcastID,
+// it reports true only if every set that answered said OK.
+//
+// cmd is a Cmd name such as "PowerOn", or a TVCmpMap key such as
+// "PowerOn01" or "VolSet32" whose data is hexadecimal as it's sent. Frames
+// are encoded on demand unless s.Cmd is set, when they come from the table
+// and cmd must be one of its keys or a Cmd name that encodes to one.
+func (s *Serial) Xmit(ctx context.Context, id int, cmd string) (bool, error) {
+	if err := s.checkID(id); err != nil {
+		return false, err
+	}
+
+	xmit, cmd2, err := s.xmitFrame(id, cmd)
+	if err != nil {
+		return false, err
+	}
+
+	if id == BroadcastID {
+		resp, err := s.transact(ctx, xmit, s.maxID())
+		if err != nil {
+			return false, err
+		}
+		acks, _ := ScanAcks(resp)
+		for _, a := range acks {
+			if !a.OK {
+				return false, nil
+			}
+		}
+		return len(acks) > 0, nil
+	}
+
+	var ok bool
+	err = s.Retry.do(ctx, func() error {
+		resp, err := s.exchange(ctx, xmit)
+		if err != nil {
+			return err
+		}
+		ok, err = s.xmitAck(id, cmd2, xmit, resp)
+		return err
+	})
+	return ok, err
 }
 
-// Xmit sends a request using the a selected serial driver
-func (s Serial) Xmit(ctx context.Context, id int, cmd string) (bool, error) {
-	return true, nil
+// xmitAck reports whether resp is an OK acknowledgement to Xmit's frame.
+func (s *Serial) xmitAck(id int, cmd2 string, xmit, resp []byte) (bool, error) {
+	if a, ok := findAck(resp, cmd2, id); ok {
+		return a.OK, nil
+	}
+	return false, &FrameError{Frame: string(resp), Reason: fmt.Sprintf("unexpected response to %q", xmit)}
+}
+
+// xmitFrame returns the frame Xmit sends for cmd and the command letter
+// the TV set will acknowledge it with.
+func (s *Serial) xmitFrame(id int, cmd string) ([]byte, string, error) {
+	if s.Cmd != nil {
+		x, ok := s.Cmd[id][cmd]
+		if !ok {
+			x, ok = s.Cmd[id][tableKey(cmd)]
+		}
+		if !ok {
+			return nil, "", fmt.Errorf("unknown command %q for TV set %d", cmd, id)
+		}
+		_, cmd2, _, _, _ := parseFrame(string(x.Xmit))
+		return x.Xmit, cmd2, nil
+	}
+
+	name, l, v, err := parseKey(cmd)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := l.frame(name, id, v)
+	return b, l.Cmd2, err
+}
+
+// tableKey returns the TVCmpMap key that cmd encodes to, e.g. "PowerOn01"
+// for "PowerOn" or "VolSet0A" for "VolSet0a", or "" if it's not a command.
+func tableKey(cmd string) string {
+	name, l, v, err := parseKey(cmd)
+	switch {
+	case err != nil:
+		return ""
+	case l.Max > 0:
+		return fmt.Sprintf("%s%02X", name, v)
+	}
+	return name + l.Data
+}
+
+// ask sends command l with value to TV set id and returns the set's
+// acknowledgement, or an error if the set answered NG. Failed attempts are
+// repeated as s.Retry allows.
+func (s *Serial) ask(ctx context.Context, id int, name string, l LGCmd, value int) (Ack, error) {
+	if err := s.checkID(id); err != nil {
+		return Ack{}, err
+	}
+	if id == BroadcastID {
+		return Ack{}, fmt.Errorf("%s: use Broadcast to address every TV set", name)
+	}
+
+	xmit, err := l.frame(name, id, value)
+	if err != nil {
+		return Ack{}, err
+	}
+
+	var a Ack
+	err = s.Retry.do(ctx, func() error {
+		resp, err := s.exchange(ctx, xmit)
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		if a, ok = findAck(resp, l.Cmd2, id); !ok {
+			return &FrameError{Frame: string(resp), Reason: "no acknowledgement for " + name}
+		}
+		if !a.OK {
+			return fmt.Errorf("%s: %w", name, ErrNG)
+		}
+		return nil
+	})
+
+	return a, err
+}
+
+// findAck returns the last acknowledgement in resp from TV set id for cmd2.
+func findAck(resp []byte, cmd2 string, id int) (Ack, bool) {
+	acks, _ := ScanAcks(resp)
+	for i := len(acks) - 1; i >= 0; i-- {
+		if a := acks[i]; a.Cmd2 == cmd2 && a.ID == id {
+			return a, true
+		}
+	}
+	return Ack{}, false
+}
+
+// Broadcast sends the named command to every TV set on the chain and
+// returns the acknowledgement of each set that answered within ReadTimeout.
+func (s *Serial) Broadcast(ctx context.Context, name string) ([]Ack, error) {
+	l, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !l.isSerial() {
+		return nil, &UnsupportedError{Name: name, Transport: SerialTransport.String()}
+	}
+
+	xmit, err := l.frame(name, BroadcastID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.transact(ctx, xmit, s.maxID())
+	if err != nil {
+		return nil, err
+	}
+
+	var acks []Ack
+	all, _ := ScanAcks(resp)
+	for _, a := range all {
+		if a.Cmd2 == l.Cmd2 {
+			acks = append(acks, a)
+		}
+	}
+	return acks, nil
+}
+
+func (s *Serial) maxID() int {
+	if s.MaxID == 0 {
+		return MaxTVs
+	}
+	return s.MaxID
+}
+
+// checkID reports an error if id isn't a set ID on the chain.
+func (s *Serial) checkID(id int) error {
+	if max := s.maxID(); id < BroadcastID || id > max || id > MaxSetID {
+		return fmt.Errorf("TV set ID %d out of range %d..%d", id, BroadcastID, max)
+	}
+	return nil
+}
+
+// exchange writes frame to the port and returns the acknowledgement that
+// answers it.
+func (s *Serial) exchange(ctx context.Context, frame []byte) ([]byte, error) {
+	return s.transact(ctx, frame, 1)
+}
+
+// readAcks reads from the port until n acknowledgements to frame arrive or
+// the deadline passes, and returns them. Other acknowledgements, such as a
+// late one to an earlier command, are discarded. If none answer frame, it
+// returns whatever arrived for the caller to report, or ErrTimeout if
+// nothing did.
+func (s *Serial) readAcks(deadline time.Time, frame []byte, n int) ([]byte, error) {
+	_, cmd2, id, _, ok := parseFrame(string(frame))
+	answers := func(a Ack) bool {
+		return !ok || (a.Cmd2 == cmd2 && (id == BroadcastID || a.ID == id))
+	}
+
+	var (
+		raw, rest, out []byte
+		acks           []Ack
+		got            int
+		b              = make([]byte, 64)
+	)
+
+	for time.Now().Before(deadline) {
+		m, err := s.conn.Read(b)
+		raw = append(raw, b[:m]...)
+		acks, rest = ScanAcks(append(rest, b[:m]...))
+		for _, a := range acks {
+			if answers(a) {
+				out = append(out, a.frame()...)
+				got++
+			}
+		}
+		if got >= n {
+			return out, nil
+		}
+
+		switch {
+		case err != nil && err != io.EOF:
+			return raw, err
+		case m == 0:
+			time.Sleep(pollInterval)
+		}
+	}
+
+	switch {
+	case got > 0:
+		return out, nil
+	case bytes.IndexByte(raw, 'x') >= 0:
+		return raw, nil
+	}
+	return raw, ErrTimeout
+}
+
+// drain discards input waiting on the port, such as an acknowledgement
+// that arrived after its command timed out, so the next command can't take
+// it for its own. A serial device is flushed; other ports are read until
+// they have nothing more.
+func (s *Serial) drain() error {
+	if f, ok := s.conn.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+
+	b := make([]byte, 64)
+	for {
+		n, err := s.conn.Read(b)
+		switch {
+		case err != nil && err != io.EOF:
+			return err
+		case n == 0:
+			return nil
+		}
+	}
 }
@@ -1,9 +1,16 @@
 package lgtv
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
+	"fmt"
+	"io"
 	"sort"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -17,9 +24,9 @@ import (
 // 			r     TVCmpMap
 // 			rTV   TVCmds
 // 		}{
-// 			{rLen: 5, rkLen: 1, name: "Single Record", rTV: TVCmds{"Single-Step": {Cmd1: "k", Cmd2: "z"}}},
-// 			{rLen: 5, rkLen: 65, name: "Step Generator", rTV: TVCmds{"Multi-Step": {Cmd1: "k", Cmd2: "q", Max: 64}}},
-// 			{rLen: 5, rkLen: 0, name: "WebOS Only", rTV: TVCmds{"WebOs": {}}},
+// 			{rLen: 6, rkLen: 1, name: "Single Record", rTV: TVCmds{"Single-Step": {Cmd1: "k", Cmd2: "z"}}},
+// 			{rLen: 6, rkLen: 65, name: "Step Generator", rTV: TVCmds{"Multi-Step": {Cmd1: "k", Cmd2: "q", Max: 64}}},
+// 			{rLen: 6, rkLen: 0, name: "WebOS Only", rTV: TVCmds{"WebOs": {}}},
 // 		}
 //
 // 		for _, tt := range tests {
@@ -43,9 +50,9 @@ func TestRespMapIDs(t *testing.T) {
 			r     RespMap
 			rTV   TVCmds
 		}{
-			{rLen: 5, rkLen: 2, name: "Single Record", rTV: TVCmds{"Single-Step": {Cmd1: "k", Cmd2: "z"}}},
-			{rLen: 5, rkLen: 130, name: "Step Generator", rTV: TVCmds{"Multi-Step": {Cmd1: "k", Cmd2: "q", Max: 64}}},
-			{rLen: 5, rkLen: 0, name: "WebOS Only", rTV: TVCmds{"WebOs": {}}},
+			{rLen: 6, rkLen: 2, name: "Single Record", rTV: TVCmds{"Single-Step": {Cmd1: "k", Cmd2: "z"}}},
+			{rLen: 6, rkLen: 202, name: "Step Generator", rTV: TVCmds{"Multi-Step": {Cmd1: "k", Cmd2: "q", Max: 64}}},
+			{rLen: 6, rkLen: 0, name: "WebOS Only", rTV: TVCmds{"WebOs": {}}},
 		}
 
 		for _, tt := range tests {
@@ -69,7 +76,7 @@ func TestRespMapString(t *testing.T) {
 		}{
 			{
 				name: "First Record",
-				want: [16]uint8{215, 244, 60, 243, 171, 65, 7, 193, 207, 206, 234, 187, 146, 48, 125, 209},
+				want: [16]uint8{89, 6, 52, 216, 188, 48, 244, 211, 164, 56, 18, 220, 1, 102, 199, 130},
 				tv: TVCmds{
 					"First": {
 						Cmd1: "k",
@@ -79,7 +86,7 @@ func TestRespMapString(t *testing.T) {
 			},
 			{
 				name: "Second Record",
-				want: [16]uint8{75, 64, 21, 55, 223, 99, 113, 37, 28, 68, 156, 180, 4, 151, 230, 111},
+				want: [16]uint8{35, 96, 41, 63, 170, 103, 206, 150, 156, 79, 135, 255, 85, 148, 10, 75},
 				tv: TVCmds{
 					"Second": {
 						Cmd1: "k",
@@ -89,12 +96,12 @@ func TestRespMapString(t *testing.T) {
 			},
 			{
 				name: "Third Record",
-				want: [16]uint8{186, 207, 19, 245, 23, 50, 99, 222, 58, 24, 56, 195, 229, 58, 113, 80},
+				want: [16]uint8{98, 188, 173, 38, 180, 151, 241, 227, 17, 222, 117, 147, 186, 201, 20, 173},
 				tv:   nil,
 			},
 			{
 				name: "Fourth Record",
-				want: [16]uint8{83, 0, 124, 146, 32, 252, 218, 80, 35, 6, 225, 28, 219, 198, 101, 210},
+				want: [16]uint8{106, 82, 171, 21, 228, 175, 106, 188, 186, 151, 125, 255, 41, 126, 92, 253},
 				tv: TVCmds{
 					"Second": {
 						Cmd1: "m",
@@ -174,7 +181,7 @@ func TestTVCmpMapString(t *testing.T) {
 		}{
 			{
 				name: "First Record",
-				want: [16]uint8{59, 194, 93, 163, 170, 13, 235, 154, 152, 174, 32, 35, 72, 133, 115, 213},
+				want: [16]uint8{70, 110, 121, 121, 145, 167, 172, 111, 64, 176, 142, 163, 200, 26, 167, 113},
 				tv: TVCmds{
 					"First": {
 						Cmd1: "k",
@@ -184,7 +191,7 @@ func TestTVCmpMapString(t *testing.T) {
 			},
 			{
 				name: "Second Record",
-				want: [16]uint8{159, 138, 231, 87, 31, 145, 29, 225, 69, 148, 218, 27, 119, 93, 241, 89},
+				want: [16]uint8{103, 99, 106, 98, 46, 227, 75, 239, 60, 106, 72, 245, 32, 25, 153, 156},
 				tv: TVCmds{
 					"Second": {
 						Cmd1: "k",
@@ -194,12 +201,12 @@ func TestTVCmpMapString(t *testing.T) {
 			},
 			{
 				name: "Third Record",
-				want: [16]uint8{186, 207, 19, 245, 23, 50, 99, 222, 58, 24, 56, 195, 229, 58, 113, 80},
+				want: [16]uint8{98, 188, 173, 38, 180, 151, 241, 227, 17, 222, 117, 147, 186, 201, 20, 173},
 				tv:   nil,
 			},
 			{
 				name: "Fourth Record",
-				want: [16]uint8{28, 129, 53, 217, 86, 33, 246, 108, 221, 252, 213, 23, 212, 204, 230, 240},
+				want: [16]uint8{165, 19, 152, 96, 14, 33, 24, 81, 164, 246, 125, 24, 175, 0, 138, 245},
 				tv: TVCmds{
 					"Second": {
 						Cmd1: "m",
@@ -228,7 +235,7 @@ func TestSetSerialCmds(t *testing.T) {
 		}{
 			{
 				name: "First Record",
-				want: []string{"k z 00 01\n", "k z 01 01\n", "k z 02 01\n", "k z 03 01\n", "k z 04 01\n", "k z NG 00 01x", "k z NG 01 01x", "k z NG 02 01x", "k z NG 03 01x", "k z NG 04 01x", "k z OK 00 01x", "k z OK 01 01x", "k z OK 02 01x", "k z OK 03 01x", "k z OK 04 01x"},
+				want: []string{"kz 00 01\r", "kz 01 01\r", "kz 02 01\r", "kz 03 01\r", "kz 04 01\r", "kz 05 01\r", "z 00 NG01x", "z 00 OK01x", "z 01 NG01x", "z 01 OK01x", "z 02 NG01x", "z 02 OK01x", "z 03 NG01x", "z 03 OK01x", "z 04 NG01x", "z 04 OK01x", "z 05 NG01x", "z 05 OK01x"},
 				tv: TVCmds{
 					"First": {
 						Cmd1: "k",
@@ -239,7 +246,7 @@ func TestSetSerialCmds(t *testing.T) {
 			},
 			{
 				name: "Second Record",
-				want: []string{"k q 00 03\n", "k q 01 03\n", "k q 02 03\n", "k q 03 03\n", "k q 04 03\n", "k q NG 00 03x", "k q NG 01 03x", "k q NG 02 03x", "k q NG 03 03x", "k q NG 04 03x", "k q OK 00 03x", "k q OK 01 03x", "k q OK 02 03x", "k q OK 03 03x", "k q OK 04 03x"},
+				want: []string{"kq 00 03\r", "kq 01 03\r", "kq 02 03\r", "kq 03 03\r", "kq 04 03\r", "kq 05 03\r", "q 00 NG03x", "q 00 OK03x", "q 01 NG03x", "q 01 OK03x", "q 02 NG03x", "q 02 OK03x", "q 03 NG03x", "q 03 OK03x", "q 04 NG03x", "q 04 OK03x", "q 05 NG03x", "q 05 OK03x"},
 				tv: TVCmds{
 					"Second": {
 						Cmd1: "k",
@@ -255,12 +262,12 @@ func TestSetSerialCmds(t *testing.T) {
 			},
 			{
 				name: "Fourth Record",
-				want: []string{"m d 00 00\n", "m d 00 10\n", "m d 00 20\n", "m d 00 30\n", "m d 00 40\n", "m d 00 50\n", "m d 00 60\n", "m d 00 70\n", "m d 00 80\n", "m d 00 90\n", "m d 01 00\n", "m d 01 10\n", "m d 01 20\n", "m d 01 30\n", "m d 01 40\n", "m d 01 50\n", "m d 01 60\n", "m d 01 70\n", "m d 01 80\n", "m d 01 90\n", "m d 02 00\n", "m d 02 10\n", "m d 02 20\n", "m d 02 30\n", "m d 02 40\n", "m d 02 50\n", "m d 02 60\n", "m d 02 70\n", "m d 02 80\n", "m d 02 90\n", "m d 03 00\n", "m d 03 10\n", "m d 03 20\n", "m d 03 30\n", "m d 03 40\n", "m d 03 50\n", "m d 03 60\n", "m d 03 70\n", "m d 03 80\n", "m d 03 90\n", "m d 04 00\n", "m d 04 10\n", "m d 04 20\n", "m d 04 30\n", "m d 04 40\n", "m d 04 50\n", "m d 04 60\n", "m d 04 70\n", "m d 04 80\n", "m d 04 90\n", "m d NG 00 00x", "m d NG 00 10x", "m d NG 00 20x", "m d NG 00 30x", "m d NG 00 40x", "m d NG 00 50x", "m d NG 00 60x", "m d NG 00 70x", "m d NG 00 80x", "m d NG 00 90x", "m d NG 01 00x", "m d NG 01 10x", "m d NG 01 20x", "m d NG 01 30x", "m d NG 01 40x", "m d NG 01 50x", "m d NG 01 60x", "m d NG 01 70x", "m d NG 01 80x", "m d NG 01 90x", "m d NG 02 00x", "m d NG 02 10x", "m d NG 02 20x", "m d NG 02 30x", "m d NG 02 40x", "m d NG 02 50x", "m d NG 02 60x", "m d NG 02 70x", "m d NG 02 80x", "m d NG 02 90x", "m d NG 03 00x", "m d NG 03 10x", "m d NG 03 20x", "m d NG 03 30x", "m d NG 03 40x", "m d NG 03 50x", "m d NG 03 60x", "m d NG 03 70x", "m d NG 03 80x", "m d NG 03 90x", "m d NG 04 00x", "m d NG 04 10x", "m d NG 04 20x", "m d NG 04 30x", "m d NG 04 40x", "m d NG 04 50x", "m d NG 04 60x", "m d NG 04 70x", "m d NG 04 80x", "m d NG 04 90x", "m d OK 00 00x", "m d OK 00 10x", "m d OK 00 20x", "m d OK 00 30x", "m d OK 00 40x", "m d OK 00 50x", "m d OK 00 60x", "m d OK 00 70x", "m d OK 00 80x", "m d OK 00 90x", "m d OK 01 00x", "m d OK 01 10x", "m d OK 01 20x", "m d OK 01 30x", "m d OK 01 40x", "m d OK 01 50x", "m d OK 01 60x", "m d OK 01 70x", "m d OK 01 80x", "m d OK 01 90x", "m d OK 02 00x", "m d OK 02 10x", "m d OK 02 20x", "m d OK 02 30x", "m d OK 02 40x", "m d OK 02 50x", "m d OK 02 60x", "m d OK 02 70x", "m d OK 02 80x", "m d OK 02 90x", "m d OK 03 00x", "m d OK 03 10x", "m d OK 03 20x", "m d OK 03 30x", "m d OK 03 40x", "m d OK 03 50x", "m d OK 03 60x", "m d OK 03 70x", "m d OK 03 80x", "m d OK 03 90x", "m d OK 04 00x", "m d OK 04 10x", "m d OK 04 20x", "m d OK 04 30x", "m d OK 04 40x", "m d OK 04 50x", "m d OK 04 60x", "m d OK 04 70x", "m d OK 04 80x", "m d OK 04 90x"},
+				want: []string{"d 00 NG00x", "d 00 NG01x", "d 00 NG02x", "d 00 NG03x", "d 00 OK00x", "d 00 OK01x", "d 00 OK02x", "d 00 OK03x", "d 01 NG00x", "d 01 NG01x", "d 01 NG02x", "d 01 NG03x", "d 01 OK00x", "d 01 OK01x", "d 01 OK02x", "d 01 OK03x", "d 02 NG00x", "d 02 NG01x", "d 02 NG02x", "d 02 NG03x", "d 02 OK00x", "d 02 OK01x", "d 02 OK02x", "d 02 OK03x", "d 03 NG00x", "d 03 NG01x", "d 03 NG02x", "d 03 NG03x", "d 03 OK00x", "d 03 OK01x", "d 03 OK02x", "d 03 OK03x", "d 04 NG00x", "d 04 NG01x", "d 04 NG02x", "d 04 NG03x", "d 04 OK00x", "d 04 OK01x", "d 04 OK02x", "d 04 OK03x", "d 05 NG00x", "d 05 NG01x", "d 05 NG02x", "d 05 NG03x", "d 05 OK00x", "d 05 OK01x", "d 05 OK02x", "d 05 OK03x", "md 00 00\r", "md 00 01\r", "md 00 02\r", "md 00 03\r", "md 01 00\r", "md 01 01\r", "md 01 02\r", "md 01 03\r", "md 02 00\r", "md 02 01\r", "md 02 02\r", "md 02 03\r", "md 03 00\r", "md 03 01\r", "md 03 02\r", "md 03 03\r", "md 04 00\r", "md 04 01\r", "md 04 02\r", "md 04 03\r", "md 05 00\r", "md 05 01\r", "md 05 02\r", "md 05 03\r"},
 				tv: TVCmds{
 					"Second": {
 						Cmd1: "m",
 						Cmd2: "d",
-						Max:  10,
+						Max:  3,
 					},
 				},
 			},
@@ -281,5 +288,210 @@ func TestSetSerialCmds(t *testing.T) {
 				So(x, ShouldResemble, tt.want)
 			})
 		}
+
+		Convey("running test: Frames match the encoder", func() {
+			tvc := Cmd.SerialCmdsFor(10)
+			var bad []string
+			for name, l := range Cmd {
+				if l.Max == 0 {
+					continue
+				}
+				for v := 0; v <= l.limit(); v++ {
+					want, _ := Encode(l, 10, v)
+					if got := tvc[10][fmt.Sprintf("%s%02X", name, v)].Xmit; !bytes.Equal(got, want) {
+						bad = append(bad, fmt.Sprintf("%s %d: %q", name, v, got))
+					}
+				}
+			}
+			So(bad, ShouldBeEmpty)
+		})
+	})
+}
+
+// fakePort answers each write with the reply registered for that frame.
+type fakePort struct {
+	mu      sync.Mutex
+	replies map[string]string
+	rx      bytes.Buffer
+	tx      []string
+	closed  bool
+}
+
+func (f *fakePort) Read(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return f.rx.Read(b)
+}
+
+func (f *fakePort) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, io.ErrClosedPipe
+	}
+	f.tx = append(f.tx, string(b))
+	f.rx.WriteString(f.replies[string(b)])
+	return len(b), nil
+}
+
+func (f *fakePort) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func TestXmit(t *testing.T) {
+	Convey("Testing Xmit()", t, func() {
+		tv := TVCmds{"PowerOn": {Cmd1: "k", Cmd2: "a", Data: "01"}}
+		tests := []struct {
+			name    string
+			cmd     string
+			id      int
+			reply   string
+			timeout time.Duration
+			want    bool
+			wantErr error
+			errMsg  string
+		}{
+			{name: "OK", cmd: "PowerOn01", id: 1, reply: "a 01 OK01x", want: true},
+			{name: "NG", cmd: "PowerOn01", id: 1, reply: "a 01 NG01x", want: false},
+			{name: "Noise before ack", cmd: "PowerOn01", id: 1, reply: "\r\na 01 OK01x", want: true},
+			{name: "Cmd name", cmd: "PowerOn", id: 1, reply: "a 01 OK01x", want: true},
+			{
+				name:    "Made-up layout",
+				cmd:     "PowerOn01",
+				id:      1,
+				reply:   "k a OK 01 01x",
+				timeout: 50 * time.Millisecond,
+				errMsg:  `malformed frame "k a OK 01 01x": unexpected response to "ka 01 01\r"`,
+			},
+			{name: "Unknown command", cmd: "PowerOff00", id: 1, errMsg: `unknown command "PowerOff00" for TV set 1`},
+			{name: "Last set on the chain", cmd: "PowerOn01", id: 5, reply: "a 05 OK01x", want: true},
+			{name: "Set out of range", cmd: "PowerOn01", id: 9, errMsg: "TV set ID 9 out of range 0..5"},
+			{name: "Silent set", cmd: "PowerOn01", id: 2, timeout: 50 * time.Millisecond, wantErr: ErrTimeout},
+			{name: "Garbled ack", cmd: "PowerOn01", id: 1, reply: "zzx", timeout: 50 * time.Millisecond, errMsg: `malformed frame "zzx": unexpected response to "ka 01 01\r"`},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				s := &Serial{
+					Cmd:         tv.SetSerialCmds(),
+					ReadTimeout: time.Second,
+					conn:        &fakePort{replies: map[string]string{"ka 0" + strconv.Itoa(tt.id) + " 01\r": tt.reply}},
+				}
+				if tt.timeout > 0 {
+					s.ReadTimeout = tt.timeout
+				}
+
+				got, err := s.Xmit(context.Background(), tt.id, tt.cmd)
+				switch {
+				case tt.wantErr != nil:
+					So(err, ShouldEqual, tt.wantErr)
+				case tt.errMsg != "":
+					So(err.Error(), ShouldEqual, tt.errMsg)
+				default:
+					So(err, ShouldBeNil)
+				}
+				So(got, ShouldEqual, tt.want)
+			})
+		}
+
+		Convey("running test: Port not open", func() {
+			s := &Serial{Cmd: tv.SetSerialCmds()}
+			_, err := s.Xmit(context.Background(), 1, "PowerOn01")
+			So(err, ShouldEqual, errNotOpen)
+		})
+
+		Convey("running test: Context cancelled while waiting", func() {
+			s := &Serial{
+				Cmd:         tv.SetSerialCmds(),
+				ReadTimeout: 10 * time.Second,
+				conn:        &fakePort{},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := s.Xmit(ctx, 1, "PowerOn01")
+			So(err, ShouldResemble, context.DeadlineExceeded)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+		})
+	})
+}
+
+func TestBroadcast(t *testing.T) {
+	Convey("Testing Broadcast()", t, func() {
+		tests := []struct {
+			name    string
+			maxID   int
+			reply   string
+			want    []Ack
+			wantErr error
+			xmit    bool
+			elapsed time.Duration
+		}{
+			{
+				name:  "Every set answers",
+				maxID: 2,
+				reply: "e 01 OK00xe 02 OK00x",
+				want:  []Ack{{Cmd2: "e", ID: 1, OK: true, Data: "00"}, {Cmd2: "e", ID: 2, OK: true, Data: "00"}},
+				xmit:  true,
+			},
+			{
+				name:    "Some sets answer",
+				maxID:   12,
+				reply:   "e 01 OK00xe 0c NG00x",
+				want:    []Ack{{Cmd2: "e", ID: 1, OK: true, Data: "00"}, {Cmd2: "e", ID: 12, Data: "00"}},
+				elapsed: 50 * time.Millisecond,
+			},
+			{
+				name:    "No set answers",
+				maxID:   99,
+				wantErr: ErrTimeout,
+				elapsed: 50 * time.Millisecond,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				newSerial := func() *Serial {
+					return &Serial{
+						Cmd:         Cmd.SerialCmdsFor(tt.maxID),
+						MaxID:       tt.maxID,
+						ReadTimeout: 50 * time.Millisecond,
+						conn:        &fakePort{replies: map[string]string{"ke 00 00\r": tt.reply}},
+					}
+				}
+
+				start := time.Now()
+				got, err := newSerial().Broadcast(context.Background(), "MuteOn")
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, tt.elapsed)
+				So(err, ShouldEqual, tt.wantErr)
+				So(got, ShouldResemble, tt.want)
+
+				ok, err := newSerial().Xmit(context.Background(), BroadcastID, "MuteOn00")
+				So(err, ShouldEqual, tt.wantErr)
+				So(ok, ShouldEqual, tt.xmit)
+			})
+		}
+
+		Convey("running test: High set IDs", func() {
+			s := &Serial{
+				MaxID:       99,
+				ReadTimeout: time.Second,
+				conn:        &fakePort{replies: map[string]string{"ke 63 00\r": "e 63 OK00x"}},
+			}
+			So(s.checkID(100), ShouldNotBeNil)
+
+			s.ID = 99
+			So(s.Do(context.Background(), "MuteOn"), ShouldBeNil)
+
+			s.ID = BroadcastID
+			So(s.Do(context.Background(), "MuteOn"), ShouldEqual, ErrTimeout)
+		})
 	})
 }
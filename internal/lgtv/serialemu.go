@@ -0,0 +1,217 @@
+package lgtv
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SerialEmulator answers the LG RS-232C protocol like a chain of TV sets,
+// so Serial can be exercised without a physical TV on a USB adapter.
+type SerialEmulator struct {
+	mu     sync.Mutex
+	state  map[int]map[string]string // set ID → Cmd1+Cmd2 → data
+	master *os.File
+	slave  *os.File
+}
+
+// emuDefaults are the settings of a freshly emulated TV set.
+var emuDefaults = map[string]string{
+	"dl": "04D2", // elapsed hours
+	"dn": "28",   // internal temperature
+	"dp": "01",   // lamp
+	"ka": "01",   // power
+	"kb": "08",   // input
+	"kc": "02",   // aspect
+	"kd": "01",   // screen
+	"ke": "01",   // mute
+	"kf": "14",   // volume
+	"kg": "46",   // contrast
+	"kh": "32",   // brightness
+	"ki": "32",   // color
+	"kj": "32",   // tint
+	"kk": "0A",   // sharpness
+	"kl": "01",   // OSD
+	"km": "01",   // remote control lock
+	"kt": "32",   // balance
+	"ku": "00",   // color temperature
+	"kz": "00",   // abnormal state
+}
+
+// NewSerialEmulator returns an emulator for TV sets with the given IDs,
+// set 1 if none are given.
+func NewSerialEmulator(ids ...int) *SerialEmulator {
+	if len(ids) == 0 {
+		ids = []int{1}
+	}
+
+	e := &SerialEmulator{state: make(map[int]map[string]string)}
+	for _, id := range ids {
+		e.state[id] = make(map[string]string)
+		for k, v := range emuDefaults {
+			e.state[id][k] = v
+		}
+	}
+	return e
+}
+
+// ListenPTY creates a pseudo-terminal, serves the emulator on it in the
+// background and returns the device path for Serial.Port.
+func (e *SerialEmulator) ListenPTY() (string, error) {
+	m, path, err := openPTY()
+	if err != nil {
+		return "", err
+	}
+
+	// Holding the slave open keeps the master readable between clients.
+	s, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		m.Close()
+		return "", err
+	}
+	if err := makeRaw(s); err != nil {
+		m.Close()
+		s.Close()
+		return "", err
+	}
+
+	e.master, e.slave = m, s
+	go e.Serve(m)
+
+	return path, nil
+}
+
+// Close shuts down the pseudo-terminal opened by ListenPTY.
+func (e *SerialEmulator) Close() error {
+	if e.master == nil {
+		return nil
+	}
+	e.slave.Close()
+	return e.master.Close()
+}
+
+// Serve answers command frames read from rw until it runs dry or fails.
+func (e *SerialEmulator) Serve(rw io.ReadWriter) error {
+	sc := bufio.NewScanner(rw)
+	sc.Split(splitFrames)
+	for sc.Scan() {
+		if resp := e.Reply(sc.Text()); resp != "" {
+			if _, err := io.WriteString(rw, resp); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// Reply returns the acknowledgements to a single command frame such as
+// "ka 01 01". Sets that aren't present stay silent.
+func (e *SerialEmulator) Reply(frame string) string {
+	cmd1, cmd2, id, data, ok := parseFrame(frame)
+	if !ok {
+		return ""
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids := []int{id}
+	if id == BroadcastID {
+		ids = e.ids()
+	}
+
+	var b strings.Builder
+	for _, id := range ids {
+		st, ok := e.state[id]
+		if !ok {
+			continue
+		}
+		a := Ack{Cmd2: cmd2, ID: id}
+		a.Data, a.OK = e.apply(st, cmd1, cmd2, data)
+		b.WriteString(a.frame())
+	}
+	return b.String()
+}
+
+// apply carries out a command on one set's state and returns the data to
+// acknowledge it with.
+func (e *SerialEmulator) apply(st map[string]string, cmd1, cmd2, data string) (string, bool) {
+	k := cmd1 + cmd2
+
+	// A set in standby only answers power commands and says why it's off.
+	if st["ka"] == "00" && k != "ka" && !(k == "kz" && data == "FF") {
+		return data, false
+	}
+
+	if data == "FF" {
+		v, ok := st[k]
+		if !ok {
+			return "00", false
+		}
+		return v, true
+	}
+
+	if !emuValid(cmd1, cmd2, data) {
+		return data, false
+	}
+
+	st[k] = data
+	if k == "ka" {
+		st["kz"] = map[string]string{"00": "04", "01": "00"}[data]
+	}
+	return data, true
+}
+
+// emuValid reports whether the Cmd table allows data for cmd1 and cmd2.
+func emuValid(cmd1, cmd2, data string) bool {
+	v, err := strconv.ParseUint(data, 16, 16)
+	if err != nil {
+		return false
+	}
+
+	for _, l := range Cmd {
+		if l.Cmd1 != cmd1 || l.Cmd2 != cmd2 {
+			continue
+		}
+		if (l.Max == 0 && l.Data == data) || (l.Max > 0 && int(v) <= l.limit()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *SerialEmulator) ids() []int {
+	var ids []int
+	for id := 1; id <= MaxSetID; id++ {
+		if _, ok := e.state[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Value returns the data the emulated set id holds for the setting that
+// Cmd entry name controls, e.g. "VolLvl" or "VolSet".
+func (e *SerialEmulator) Value(id int, name string) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	l := Cmd[name]
+	v, ok := e.state[id][l.Cmd1+l.Cmd2]
+	return v, ok
+}
+
+// SetValue presets the data the emulated set id holds for the setting
+// that Cmd entry name controls.
+func (e *SerialEmulator) SetValue(id int, name, data string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if st, ok := e.state[id]; ok {
+		l := Cmd[name]
+		st[l.Cmd1+l.Cmd2] = data
+	}
+}
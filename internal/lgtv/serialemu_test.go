@@ -0,0 +1,110 @@
+package lgtv
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSerialEmulatorReply(t *testing.T) {
+	Convey("Testing SerialEmulator.Reply()", t, func() {
+		e := NewSerialEmulator(1, 3, 16)
+		tests := []struct {
+			name  string
+			frame string
+			want  string
+		}{
+			{name: "Read", frame: "kf 01 FF", want: "f 01 OK14x"},
+			{name: "Lower case data", frame: "kf 03 ff", want: "f 03 OK14x"},
+			{name: "Spaced layout", frame: "k f 01 FF", want: ""},
+			{name: "Set", frame: "kf 01 1E", want: "f 01 OK1Ex"},
+			{name: "Read back", frame: "kf 01 FF", want: "f 01 OK1Ex"},
+			{name: "Out of range", frame: "kf 01 65", want: "f 01 NG65x"},
+			{name: "Unknown data", frame: "ka 01 07", want: "a 01 NG07x"},
+			{name: "Unknown command", frame: "qq 01 01", want: "q 01 NG01x"},
+			{name: "Absent set", frame: "ka 02 FF", want: ""},
+			{name: "Hexadecimal set ID", frame: "ka 10 FF", want: "a 10 OK01x"},
+			{name: "Set 10 is 0a", frame: "ka 0a FF", want: ""},
+			{name: "Garbage", frame: "hello", want: ""},
+			{name: "Power off", frame: "ka 01 00", want: "a 01 OK00x"},
+			{name: "Powered off set refuses", frame: "kf 01 FF", want: "f 01 NGFFx"},
+			{name: "Abnormal state follows power", frame: "ka 01 FF", want: "a 01 OK00x"},
+			{name: "Broadcast", frame: "ke 00 00", want: "e 01 NG00xe 03 OK00xe 10 OK00x"},
+		}
+
+		Convey("running test: sequence of frames", func() {
+			for _, tt := range tests {
+				So(e.Reply(tt.frame), ShouldEqual, tt.want)
+			}
+
+			v, _ := e.Value(3, "MuteStatus")
+			So(v, ShouldEqual, "00")
+		})
+	})
+}
+
+func TestSerialEmulatorServe(t *testing.T) {
+	Convey("Testing SerialEmulator.Serve()", t, func() {
+		var out bytes.Buffer
+		in := bytes.NewBufferString("ka 01 FF\rkf 01 FF\n\nkb 01 FF")
+		So(NewSerialEmulator().Serve(&readWriter{in, &out}), ShouldBeNil)
+		So(out.String(), ShouldEqual, "a 01 OK01xf 01 OK14xb 01 OK08x")
+	})
+}
+
+func TestSerialEmulatorPTY(t *testing.T) {
+	e := NewSerialEmulator(1)
+	path, err := e.ListenPTY()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	defer e.Close()
+
+	Convey("Testing Serial against a SerialEmulator pty", t, func() {
+		s := &Serial{
+			Baud:        9600,
+			Cmd:         Cmd.SetSerialCmds(),
+			ID:          1,
+			Port:        path,
+			ReadTimeout: time.Second,
+		}
+		So(s.Open(), ShouldBeNil)
+		defer s.Close()
+
+		ctx := context.Background()
+
+		ok, err := s.Xmit(ctx, 1, "MuteOn00")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		v, _ := e.Value(1, "MuteStatus")
+		So(v, ShouldEqual, "00")
+
+		r, err := s.Query(ctx, 1, "MuteStatus")
+		So(err, ShouldBeNil)
+		So(r.Key, ShouldEqual, "MuteOn")
+
+		So(s.Do(ctx, "PowerOff"), ShouldBeNil)
+		st, err := s.State(ctx, 1)
+		So(err, ShouldBeNil)
+		So(st.Power, ShouldEqual, "PowerOff")
+		So(st.Abnormal, ShouldEqual, "Abnormal4")
+	})
+}
+
+type readWriter struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (rw *readWriter) Read(b []byte) (int, error) {
+	return rw.r.Read(b)
+}
+
+func (rw *readWriter) Write(b []byte) (int, error) {
+	return rw.w.Write(b)
+}
@@ -0,0 +1,95 @@
+package lgtv
+
+import (
+	"context"
+	"fmt"
+)
+
+// RangeError reports a value outside the bounds of a ranged command.
+type RangeError struct {
+	Name  string
+	Value int
+	Max   int
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s: %d is out of range 0..%d", e.Name, e.Value, e.Max)
+}
+
+// encode formats n as ranged command data, after checking it's in range.
+func (l LGCmd) encode(name string, n int) (string, error) {
+	if l.Max == 0 {
+		return "", fmt.Errorf("%q is not a ranged command", name)
+	}
+	if n < 0 || n > l.limit() {
+		return "", &RangeError{Name: name, Value: n, Max: l.limit()}
+	}
+	return fmt.Sprintf("%02X", n), nil
+}
+
+// Set sends the ranged command name, e.g. "VolSet", with value n to TV set
+// id. n runs from 0 to the command's limit, e.g. 100 for "VolSet".
+func (s *Serial) Set(ctx context.Context, id int, name string, n int) error {
+	l, err := lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if l.Max == 0 {
+		return fmt.Errorf("%q is not a ranged command", name)
+	}
+
+	_, err = s.ask(ctx, id, name, l, n)
+	return err
+}
+
+// SetVolume sets the volume of TV set id to n, 0 to 100.
+func (s *Serial) SetVolume(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "VolSet", n)
+}
+
+// SetBrightness sets the brightness of TV set id to n, 0 to 100.
+func (s *Serial) SetBrightness(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "BrightSet", n)
+}
+
+// SetContrast sets the contrast of TV set id to n, 0 to 100.
+func (s *Serial) SetContrast(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "ContrastSet", n)
+}
+
+// SetColor sets the colour of TV set id to n, 0 to 100.
+func (s *Serial) SetColor(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "ColorSet", n)
+}
+
+// SetTint sets the tint of TV set id to n, 0 (red) to 100 (green).
+func (s *Serial) SetTint(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "TintSet", n)
+}
+
+// SetSharpness sets the sharpness of TV set id to n, 0 to 100.
+func (s *Serial) SetSharpness(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "SharpSet", n)
+}
+
+// SetBalance sets the audio balance of TV set id to n, 0 (left) to 100
+// (right).
+func (s *Serial) SetBalance(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "BalanceSet", n)
+}
+
+// SetTileID sets the position of TV set id within a tiled wall, 0 to 16.
+func (s *Serial) SetTileID(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "TileID", n)
+}
+
+// SetTileSizeH sets the horizontal tile size of TV set id to n, 0 to 100.
+func (s *Serial) SetTileSizeH(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "TileSizeH", n)
+}
+
+// SetTileSizeV sets the vertical tile size of TV set id to n, 0 to 100.
+func (s *Serial) SetTileSizeV(ctx context.Context, id, n int) error {
+	return s.Set(ctx, id, "TileSizeV", n)
+}
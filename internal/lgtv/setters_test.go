@@ -0,0 +1,102 @@
+package lgtv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSet(t *testing.T) {
+	Convey("Testing Set()", t, func() {
+		tests := []struct {
+			name    string
+			set     func(*Serial) error
+			xmit    string
+			errMsg  string
+			rangErr *RangeError
+		}{
+			{name: "Volume", set: func(s *Serial) error { return s.SetVolume(context.Background(), 1, 30) }, xmit: "kf 01 1E\r"},
+			{name: "Volume max", set: func(s *Serial) error { return s.SetVolume(context.Background(), 1, 100) }, xmit: "kf 01 64\r"},
+			{name: "Brightness min", set: func(s *Serial) error { return s.SetBrightness(context.Background(), 1, 0) }, xmit: "kh 01 00\r"},
+			{name: "Contrast", set: func(s *Serial) error { return s.SetContrast(context.Background(), 1, 70) }, xmit: "kg 01 46\r"},
+			{name: "Color", set: func(s *Serial) error { return s.SetColor(context.Background(), 1, 50) }, xmit: "ki 01 32\r"},
+			{name: "Tint", set: func(s *Serial) error { return s.SetTint(context.Background(), 1, 50) }, xmit: "kj 01 32\r"},
+			{name: "Sharpness", set: func(s *Serial) error { return s.SetSharpness(context.Background(), 1, 10) }, xmit: "kk 01 0A\r"},
+			{name: "Balance", set: func(s *Serial) error { return s.SetBalance(context.Background(), 1, 50) }, xmit: "kt 01 32\r"},
+			{name: "Tile ID", set: func(s *Serial) error { return s.SetTileID(context.Background(), 1, 16) }, xmit: "di 01 10\r"},
+			{name: "Tile size H", set: func(s *Serial) error { return s.SetTileSizeH(context.Background(), 1, 5) }, xmit: "dg 01 05\r"},
+			{name: "Tile size V", set: func(s *Serial) error { return s.SetTileSizeV(context.Background(), 1, 5) }, xmit: "dh 01 05\r"},
+			{
+				name:    "Volume too loud",
+				set:     func(s *Serial) error { return s.SetVolume(context.Background(), 1, 101) },
+				rangErr: &RangeError{Name: "VolSet", Value: 101, Max: 100},
+			},
+			{
+				name:    "Negative brightness",
+				set:     func(s *Serial) error { return s.SetBrightness(context.Background(), 1, -1) },
+				rangErr: &RangeError{Name: "BrightSet", Value: -1, Max: 100},
+			},
+			{
+				name:    "Tile ID beyond wall",
+				set:     func(s *Serial) error { return s.SetTileID(context.Background(), 1, 17) },
+				rangErr: &RangeError{Name: "TileID", Value: 17, Max: 16},
+			},
+			{
+				name:   "Not ranged",
+				set:    func(s *Serial) error { return s.Set(context.Background(), 1, "PowerOn", 1) },
+				errMsg: `"PowerOn" is not a ranged command`,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				p := &fakePort{replies: map[string]string{}}
+				if tt.xmit != "" {
+					p.replies[tt.xmit] = string(tt.xmit[1]) + " 01 OK" + tt.xmit[6:8] + "x"
+				}
+				s := &Serial{ReadTimeout: time.Second, conn: p}
+
+				err := tt.set(s)
+				switch {
+				case tt.rangErr != nil:
+					So(err, ShouldResemble, tt.rangErr)
+					So(err.Error(), ShouldStartWith, tt.rangErr.Name+": ")
+					So(p.tx, ShouldBeEmpty)
+				case tt.errMsg != "":
+					So(err.Error(), ShouldEqual, tt.errMsg)
+				default:
+					So(err, ShouldBeNil)
+					So(p.tx, ShouldResemble, []string{tt.xmit})
+				}
+			})
+		}
+	})
+}
+
+func TestSetEmulated(t *testing.T) {
+	Convey("Testing SetVolume() against a SerialEmulator", t, func() {
+		e := NewSerialEmulator()
+		s := &Serial{ReadTimeout: time.Second, conn: &emuPort{e: e}}
+
+		So(s.SetVolume(context.Background(), 1, 100), ShouldBeNil)
+		r, err := s.Query(context.Background(), 1, "VolLvl")
+		So(err, ShouldBeNil)
+		So(r.Value, ShouldEqual, 100)
+	})
+}
+
+// emuPort answers frames written to it with a SerialEmulator's replies.
+type emuPort struct {
+	fakePort
+	e *SerialEmulator
+}
+
+func (p *emuPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tx = append(p.tx, string(b))
+	p.rx.WriteString(p.e.Reply(string(b)))
+	return len(b), nil
+}
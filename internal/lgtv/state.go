@@ -0,0 +1,83 @@
+package lgtv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// TVState is a snapshot of every readable setting of a TV set.
+type TVState struct {
+	ID          int               `json:"id"`
+	Time        time.Time         `json:"time"`
+	Power       string            `json:"power,omitempty"`
+	Input       string            `json:"input,omitempty"`
+	Aspect      string            `json:"aspect,omitempty"`
+	Mute        string            `json:"mute,omitempty"`
+	Volume      int               `json:"volume"`
+	Contrast    int               `json:"contrast"`
+	Brightness  int               `json:"brightness"`
+	Color       int               `json:"color"`
+	Tint        int               `json:"tint"`
+	Sharpness   int               `json:"sharpness"`
+	Balance     int               `json:"balance"`
+	ColorTemp   string            `json:"colorTemp,omitempty"`
+	Abnormal    string            `json:"abnormal,omitempty"`
+	Lamp        string            `json:"lamp,omitempty"`
+	Temperature int               `json:"temperature"`
+	Hours       int               `json:"hours"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// stateReads maps read commands to the TVState fields they fill in.
+var stateReads = []struct {
+	name string
+	set  func(*TVState, Reading)
+}{
+	{"PowerStatus", func(t *TVState, r Reading) { t.Power = r.String() }},
+	{"InputStatus", func(t *TVState, r Reading) { t.Input = r.String() }},
+	{"AspectStatus", func(t *TVState, r Reading) { t.Aspect = r.String() }},
+	{"MuteStatus", func(t *TVState, r Reading) { t.Mute = r.String() }},
+	{"VolLvl", func(t *TVState, r Reading) { t.Volume = r.Value }},
+	{"ContrastLvl", func(t *TVState, r Reading) { t.Contrast = r.Value }},
+	{"BrightLevel", func(t *TVState, r Reading) { t.Brightness = r.Value }},
+	{"ColorLevel", func(t *TVState, r Reading) { t.Color = r.Value }},
+	{"TintLevel", func(t *TVState, r Reading) { t.Tint = r.Value }},
+	{"SharpLevel", func(t *TVState, r Reading) { t.Sharpness = r.Value }},
+	{"BalanceLevel", func(t *TVState, r Reading) { t.Balance = r.Value }},
+	{"ColorTempLvl", func(t *TVState, r Reading) { t.ColorTemp = r.String() }},
+	{"AbnormalRead", func(t *TVState, r Reading) { t.Abnormal = r.String() }},
+	{"LampCheck", func(t *TVState, r Reading) { t.Lamp = r.String() }},
+	{"InternalTemp", func(t *TVState, r Reading) { t.Temperature = r.Value }},
+	{"TimeElapsed", func(t *TVState, r Reading) { t.Hours = r.Value }},
+}
+
+// State reads every readable setting of TV set id. Settings the set can't
+// report are listed in TVState.Errors; State only fails if the port is
+// unusable, closes or fails, or ctx ends.
+func (s *Serial) State(ctx context.Context, id int) (TVState, error) {
+	t := TVState{ID: id, Time: time.Now()}
+
+	for _, sr := range stateReads {
+		r, err := s.Query(ctx, id, sr.name)
+		switch {
+		case err == nil:
+			sr.set(&t, r)
+		case err == errNotOpen || errors.Is(err, ErrPortClosed) || ctx.Err() != nil:
+			return t, err
+		default:
+			if t.Errors == nil {
+				t.Errors = make(map[string]string)
+			}
+			t.Errors[sr.name] = err.Error()
+		}
+	}
+
+	return t, nil
+}
+
+func (t TVState) String() string {
+	b, _ := json.MarshalIndent(t, "", "\t")
+	return string(b)
+}
@@ -0,0 +1,79 @@
+package lgtv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestState(t *testing.T) {
+	Convey("Testing State()", t, func() {
+		replies := map[string]string{
+			"ka 02 FF\r": "a 02 OK01x",
+			"kb 02 FF\r": "b 02 OK08x",
+			"kc 02 FF\r": "c 02 OK02x",
+			"ke 02 FF\r": "e 02 OK01x",
+			"kf 02 FF\r": "f 02 OK14x",
+			"kg 02 FF\r": "g 02 OK46x",
+			"kh 02 FF\r": "h 02 OK32x",
+			"ki 02 FF\r": "i 02 OK3Cx",
+			"kj 02 FF\r": "j 02 NG00x",
+			"kk 02 FF\r": "k 02 OK0Ax",
+			"kt 02 FF\r": "t 02 OK32x",
+			"ku 02 FF\r": "u 02 OK00x",
+			"kz 02 FF\r": "z 02 OK00x",
+			"dp 02 FF\r": "p 02 OK01x",
+			"dn 02 FF\r": "n 02 OK2Dx",
+		}
+
+		s := &Serial{ReadTimeout: 50 * time.Millisecond, conn: &fakePort{replies: replies}}
+
+		Convey("running test: Snapshot", func() {
+			got, err := s.State(context.Background(), 2)
+			So(err, ShouldBeNil)
+			So(got.Time.IsZero(), ShouldBeFalse)
+
+			got.Time = time.Time{}
+			So(got, ShouldResemble, TVState{
+				ID:          2,
+				Power:       "PowerOn",
+				Input:       "InputHDMI(DTV)",
+				Aspect:      "Aspect16:9",
+				Mute:        "MuteOff",
+				Volume:      20,
+				Contrast:    70,
+				Brightness:  50,
+				Color:       60,
+				Sharpness:   10,
+				Balance:     50,
+				ColorTemp:   "ColorNormal",
+				Abnormal:    "Abnormal0",
+				Lamp:        "LampOk",
+				Temperature: 45,
+				Errors: map[string]string{
+					"TintLevel":   "TintLevel: TV set answered NG",
+					"TimeElapsed": ErrTimeout.Error(),
+				},
+			})
+
+			b, err := json.Marshal(got)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldContainSubstring, `"power":"PowerOn"`)
+		})
+
+		Convey("running test: Port not open", func() {
+			_, err := (&Serial{}).State(context.Background(), 2)
+			So(err, ShouldEqual, errNotOpen)
+		})
+
+		Convey("running test: Port closed", func() {
+			got, err := (&Serial{ReadTimeout: 50 * time.Millisecond, conn: &fakePort{closed: true}}).State(context.Background(), 2)
+			So(errors.Is(err, ErrPortClosed), ShouldBeTrue)
+			So(got.Errors, ShouldBeEmpty)
+		})
+	})
+}
@@ -2,55 +2,80 @@ package lgtv
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	logging "github.com/op/go-logging"
 )
 
 const (
+	// maxTries is how many times ShowPIN checks for an answer
+	maxTries = 10
+
+	// UDAP API paths
+	udapPair    = "/udap/api/pairing"
+	udapCommand = "/udap/api/command"
+
 	agent   = `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_11_6) AppleWebKit/601.7.7 (KHTML, like Gecko) Version/9.1.2 Safari/601.7.7`
 	cr      = "\r\n"
 	httpStr = `http://`
 	udp4    = "udp4"
 )
 
-// WebOS struct for the LG TV WebOS API interface
+// webTimeout bounds each request to the TV unless WebOS.Timeout is set
+var webTimeout = 5 * time.Second
+
+// WebOS struct for the LG TV WebOS API interface. Each WebOS owns its
+// discovery socket and HTTP client and is safe for concurrent use; call
+// Close when done.
 type WebOS struct {
 	*logging.Logger
 	AppID   string
 	AppName string
 	Found   bool
 	ID      string
-	IP      net.IP
-	Name    string
-	Pin     string
+
+	// Interface picks the interface ShowPIN searches from, by name or by
+	// a CIDR its address falls in; the default route's if it's empty.
+	Interface string
+
+	IP    net.IP
+	MAC   net.HardwareAddr // for PowerOn; looked up by ARP if nil
+	Name  string
+	Pin   string
+	Port  int // UDAP HTTP port, 8080 if zero
+	Retry RetryPolicy
+
+	// Timeout bounds each request to the TV, and is how long ShowPIN
+	// pauses between checks for an answer; 5s if zero.
 	Timeout time.Duration
-}
 
-var (
-	conn     *net.UDPConn
-	maxTries = 10
-	mode     = CmdMode{Pair: "/udap/api/pairing", Send: "/udap/api/command"}
-	sock     = false
-)
+	// mu guards the fields below and those that discovery sets. It's
+	// never held across a network round trip.
+	mu     sync.Mutex
+	conn   *net.UDPConn
+	bcast  net.IP
+	client *http.Client
+}
 
-func (w *WebOS) chkMsgs() (bool, error) {
+func (w *WebOS) chkMsgs(conn *net.UDPConn) (bool, error) {
 	var (
 		buf [1024]byte
 		err error
 		ok  bool
 	)
 
+	conn.SetReadDeadline(time.Now().Add(time.Second))
 	n, addr, _ := conn.ReadFromUDP(buf[0:])
 	ip, err := w.getLocalIP()
 	if n > 0 && addr.IP.String() != ip {
@@ -64,6 +89,14 @@ func (w *WebOS) chkMsgs() (bool, error) {
 func (w *WebOS) getLocalIP() (string, error) {
 	var s string
 
+	if w.Interface != "" {
+		a, err := w.iface()
+		if err != nil {
+			return s, err
+		}
+		return a.Net.IP.String(), nil
+	}
+
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
 		return s, err
@@ -80,37 +113,81 @@ func (w *WebOS) getLocalIP() (string, error) {
 	return s, errors.New("unable to detect a connected ethernet interface")
 }
 
-// Pair using the LG TV's PIN
-func (w *WebOS) Pair() {
-	msg := []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><envelope><api type="pairing"><name>hello</name><value>%v</value><port>8080</port></api></envelope>`, w.Pin))
+// iface returns the IPv4 address on Interface to search from.
+func (w *WebOS) iface() (localAddr, error) {
+	addrs, err := selectAddrs(w.Interface)
+	if err != nil {
+		return localAddr{}, err
+	}
+	for _, a := range addrs {
+		if a.broadcast() != nil {
+			return a, nil
+		}
+	}
+	return localAddr{}, fmt.Errorf("no IPv4 address on interface %q", w.Interface)
+}
+
+// Close releases the discovery socket and idle HTTP connections.
+func (w *WebOS) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	w.Infof("Pairing with TV: %v using Pin: %v", w.Name, w.Pin)
+	if w.client != nil {
+		w.client.CloseIdleConnections()
+	}
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
 
-	w.Send(mode.Pair, msg)
+// Pair using the LG TV's PIN. It fails if the TV refuses the PIN, so the
+// caller knows whether the PIN is worth keeping.
+func (w *WebOS) Pair() error {
+	code, err := w.pair(context.Background())
+	if err == nil && code != 200 {
+		err = fmt.Errorf("%w: pairing: HTTP %d", errRefused, code)
+	}
+	return err
+}
+
+func (w *WebOS) pair(ctx context.Context) (int, error) {
+	w.mu.Lock()
+	name, pin := w.Name, w.Pin
+	w.mu.Unlock()
+
+	msg := []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><envelope><api type="pairing"><name>hello</name><value>%v</value><port>8080</port></api></envelope>`, pin))
+
+	w.Infof("Pairing with TV: %v using Pin: %v", name, pin)
+
+	code, _, err := w.send(ctx, udapPair, msg)
+	return code, err
 }
 
-func (w *WebOS) scan(portAddr string, msg []byte) error {
-	var timeout time.Time
-	timeout.Add(7 * time.Second)
-	conn.SetWriteDeadline(timeout)
+func (w *WebOS) scan(conn *net.UDPConn, bcast net.IP, portAddr string, msg []byte) error {
+	conn.SetWriteDeadline(time.Now().Add(7 * time.Second))
+
+	if bcast == nil {
+		bcast = net.IPv4bcast
+	}
 
-	udpAddr, err := net.ResolveUDPAddr(udp4, fmt.Sprintf("%v:%v", net.IPv4bcast.String(), portAddr))
+	udpAddr, err := net.ResolveUDPAddr(udp4, fmt.Sprintf("%v:%v", bcast.String(), portAddr))
 	if err != nil {
-		w.Fatal(err)
+		return err
 	}
 
-	w.Infof("Broadcasting %q on: %v:%v", msg, net.IPv4bcast.String(), portAddr)
+	w.Infof("Broadcasting %q on: %v:%v", msg, bcast.String(), portAddr)
 
 	_, err = conn.WriteToUDP(msg, udpAddr)
-	defer conn.Close()
-
 	return err
 }
 
 func (w *WebOS) pairingRequest() error {
 	xmitStr := []byte(`<?xml version="1.0" encoding="utf-8"?><envelope><api type="pairing"><name>showKey</name></api></envelope>`)
 
-	if code, _, err := w.Send(mode.Pair, xmitStr); err != nil || code != 200 {
+	if code, _, err := w.send(context.Background(), udapPair, xmitStr); err != nil || code != 200 {
 		return fmt.Errorf("Pairing error: %v", err)
 	}
 
@@ -125,15 +202,25 @@ func (w *WebOS) parseMsg(msg string, addr *net.UDPAddr) (bool, error) {
 
 	if addr.Port == 1990 {
 		rx := regexp.MustCompile(`SERVER: [\w//.]* [\w//.]* ([\w-]*)`)
+		m := rx.FindStringSubmatch(msg)
+		if m == nil {
+			return false, nil
+		}
+		name := m[1]
+		w.mu.Lock()
 		w.Found = true
 		w.IP = addr.IP
-		w.Name = rx.FindStringSubmatch(msg)[1]
-		w.Infof("LG TV %v with IP %v responded", w.Name, w.IP.String())
+		w.Name = name
+		w.mu.Unlock()
+		w.Infof("LG TV %v with IP %v responded", name, addr.IP.String())
 		w.pairingRequest()
 	}
 
-	if addr.IP.String() == w.IP.String() && w.Found == true {
-		w.Infof("LG TV %v with IP %v says: %q", w.Name, addr.IP, msg)
+	w.mu.Lock()
+	name, said := w.Name, addr.IP.Equal(w.IP) && w.Found
+	w.mu.Unlock()
+	if said {
+		w.Infof("LG TV %v with IP %v says: %q", name, addr.IP, msg)
 	}
 
 	return true, nil
@@ -141,17 +228,23 @@ func (w *WebOS) parseMsg(msg string, addr *net.UDPAddr) (bool, error) {
 
 // Send xmits a WebOS request to the LG TV.
 func (w *WebOS) Send(cmd string, msg []byte) (int, io.Reader, error) {
+	return w.send(context.Background(), cmd, msg)
+}
+
+func (w *WebOS) send(ctx context.Context, cmd string, msg []byte) (int, io.Reader, error) {
+	ip, client := w.target()
+
 	var (
 		body    []byte
 		err     error
-		lgtvCMD = fmt.Sprintf("%v%v:8080%v%v", httpStr, w.IP.String(), cmd, bytes.NewReader(msg))
+		lgtvCMD = fmt.Sprintf("%v%v:%d%v", httpStr, ip.String(), w.port(), cmd)
 		resp    *http.Response
 		req     *http.Request
 	)
 
 	w.Infof("About to contact LG TV on address: %s with command: %s", lgtvCMD, string(msg))
 
-	if req, err = http.NewRequest("POST", lgtvCMD, nil); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, "POST", lgtvCMD, bytes.NewReader(msg)); err != nil {
 		return http.StatusNotAcceptable, strings.NewReader(fmt.Sprintf("Unable to form HTTP request %v", lgtvCMD)), err
 	}
 
@@ -160,94 +253,153 @@ func (w *WebOS) Send(cmd string, msg []byte) (int, io.Reader, error) {
 	req.Header.Add("Connection", "Close")
 	req.Header.Add("User-Agent", agent)
 
-	if resp, err = (&http.Client{}).Do(req); err != nil {
-		return resp.StatusCode, strings.NewReader(fmt.Sprintf("Unable to get response from %v", w.IP.String())), err
+	if resp, err = client.Do(req); err != nil {
+		return http.StatusServiceUnavailable, strings.NewReader(fmt.Sprintf("Unable to get response from %v", ip)), err
 	}
 
 	defer resp.Body.Close()
 
 	body, err = ioutil.ReadAll(resp.Body)
 	if len(body) < 1 {
-		return resp.StatusCode, strings.NewReader(fmt.Sprintf("%s did not confirm command received", w.IP.String())), err
+		return resp.StatusCode, strings.NewReader(fmt.Sprintf("%s did not confirm command received", ip)), err
 
 	}
 
 	return resp.StatusCode, bytes.NewBuffer(body), err
 }
 
-func (w *WebOS) setUpSox() {
-	ip, err := w.getLocalIP()
-	if err != nil {
-		w.Fatal(err)
+// target returns the TV's IP address and the client to reach it with.
+func (w *WebOS) target() (net.IP, *http.Client) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.client == nil {
+		w.client = &http.Client{Timeout: w.timeout()}
 	}
+	return w.IP, w.client
+}
 
-	w.Infof("Found IP: %v", ip)
+func (w *WebOS) timeout() time.Duration {
+	if w.Timeout == 0 {
+		return webTimeout
+	}
+	return w.Timeout
+}
 
-	udpAddr, err := net.ResolveUDPAddr(udp4, ":1990")
-	if err != nil {
-		fmt.Println("Resolve:", err)
-		os.Exit(1)
+func (w *WebOS) port() int {
+	if w.Port == 0 {
+		return 8080
+	}
+	return w.Port
+}
+
+// setUpSox opens the socket that discovery replies arrive on, unless it's
+// open already, and returns it with the address to broadcast to.
+func (w *WebOS) setUpSox() (*net.UDPConn, net.IP, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		return w.conn, w.bcast, nil
 	}
-	conn, err = net.ListenUDP("udp", udpAddr)
+
+	ip, err := w.getLocalIP()
 	if err != nil {
-		fmt.Println("Listen:", err)
-		os.Exit(1)
+		return nil, nil, err
 	}
 
-	sock = true
-}
+	w.Infof("Found IP: %v", ip)
 
-// ShowPIN displays the LG TV's PIN (Pairing ID Number) on its screen.
-func (w *WebOS) ShowPIN() {
-	if !sock {
-		w.setUpSox()
+	laddr := &net.UDPAddr{}
+	if w.Interface != "" {
+		a, err := w.iface()
+		if err != nil {
+			return nil, nil, err
+		}
+		laddr, w.bcast = a.udpAddr(), a.broadcast()
 	}
 
-	_ = []byte(`M-SEARCH * HTTP/1.1` + cr +
-		`HOST: 239.255.255.250:1900` + cr +
-		`MAN: "ssdp:discover"` + cr +
-		`MX: 2` + cr +
-		`ST: urn:schemas-upnp-org:device:MediaRenderer:1` + cr + cr)
+	w.conn, err = net.ListenUDP(udp4, laddr)
+	return w.conn, w.bcast, err
+}
 
-	xmitStr := []byte(`B-SEARCH * HTTP/1.1` + cr +
-		`HOST: 239.255.255.250:1990` + cr +
-		`MAN: "ssdp:discover` + cr + `MX: 3` + cr +
-		`ST: urn:schemas-udap:service:smartText:1` + cr +
-		`USER-AGENT:` + agent + cr + cr)
+// found reports whether a TV has answered ShowPIN's search.
+func (w *WebOS) found() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.Found
+}
 
-	w.scan("1990", xmitStr)
+// ShowPIN displays the LG TV's PIN (Pairing ID Number) on its screen. It
+// pairs with the first TV to answer; use Discover to list them all.
+func (w *WebOS) ShowPIN() error {
+	conn, bcast, err := w.setUpSox()
+	if err != nil {
+		return err
+	}
+
+	if err := w.scan(conn, bcast, "1990", bSearch); err != nil {
+		return err
+	}
 
 	i := 0
-	for !w.Found && i != maxTries {
-		w.chkMsgs()
+	for !w.found() && i != maxTries {
+		w.chkMsgs(conn)
 		i++
-		switch {
-		case !w.Found && i != maxTries:
+		switch found := w.found(); {
+		case !found && i != maxTries:
 			w.Warning("No LG TV detected yet...")
-			time.Sleep(w.Timeout * time.Second)
-		case !w.Found && i == maxTries:
+			time.Sleep(w.timeout())
+		case !found && i == maxTries:
 			w.Critical("No LG TV detected, giving up!")
-		case w.Found:
+			return errors.New("no LG TV detected")
+		case found:
+			w.mu.Lock()
 			w.Infof("LG TV %v with IDL %v found at %v", w.Name, w.ID, w.IP)
+			w.mu.Unlock()
 		}
 	}
+	return nil
 }
 
 // Zap xmits a WebOS command.
 func (w *WebOS) Zap(cmd int) bool {
+	return w.zap(context.Background(), cmd) == nil
+}
+
+// zap sends key code cmd, pairing again if the TV has forgotten us. A TV
+// that doesn't answer in time is ErrTimeout; one that can't be reached or
+// won't take the key code is errRefused.
+func (w *WebOS) zap(ctx context.Context, cmd int) error {
 	i := strconv.Itoa(cmd)
-	xmitStr := []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><envelope><api type="command"><name>HandleKeyInput</name><value>%v</value></api></envelope>`, i))
 	zap := []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?><envelope><api type="command"><name>HandleKeyInput</name><value>%v</value></api></envelope>`, i))
 
-	w.Infof("Sending command %v to %v", i, w.Name)
+	w.mu.Lock()
+	name := w.Name
+	w.mu.Unlock()
+
+	w.Infof("Sending command %v to %v", i, name)
 
-	resp, _, _ := w.Send(mode.Send, zap)
+	resp, _, err := w.send(ctx, udapCommand, zap)
 
-	// Pairing required after the LG TV has been turned off
-	if resp != 200 {
-		w.Pair()
-		resp, _, _ = w.Send(mode.Send, xmitStr)
+	// Pairing required after the LG TV has been turned off; a TV that
+	// didn't answer at all won't answer pairing either
+	if err == nil && resp != 200 {
+		if _, err = w.pair(ctx); err == nil {
+			resp, _, err = w.send(ctx, udapCommand, zap)
+		}
 	}
 
-	return resp == 200
+	// The client's own Timeout and the caller's deadline both mean the TV
+	// didn't answer in time
+	var nerr net.Error
+	switch {
+	case resp == 200:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout()):
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	case err != nil:
+		return fmt.Errorf("%w: %v", errRefused, err)
+	}
+	return errRefused
 }
@@ -0,0 +1,272 @@
+package lgtv
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// WebOSEmulator is an in-process stand-in for a networked LG TV. It answers
+// UDAP B-SEARCH and SSDP M-SEARCH discovery and serves the UDAP pairing and
+// command API, so WebOS can be exercised without a real set on the LAN.
+type WebOSEmulator struct {
+	Name string // model name announced in the SERVER header
+	Pin  string // PIN required to pair
+
+	// MAC is the address a Wake-on-LAN packet to the discovery port must
+	// carry to bring the emulator out of Standby
+	MAC net.HardwareAddr
+
+	mu      sync.Mutex
+	keys    []int
+	paired  bool
+	showing bool
+	standby bool
+	http    net.Listener
+	addr    *net.TCPAddr
+	mux     *http.ServeMux
+	udp     *net.UDPConn
+}
+
+// udapEnvelope is the XML body of a UDAP request.
+type udapEnvelope struct {
+	API struct {
+		Type  string `xml:"type,attr"`
+		Name  string `xml:"name"`
+		Value string `xml:"value"`
+	} `xml:"api"`
+}
+
+// NewWebOSEmulator returns an emulated TV that pairs with pin.
+func NewWebOSEmulator(name, pin string) *WebOSEmulator {
+	return &WebOSEmulator{Name: name, Pin: pin}
+}
+
+// Listen starts answering discovery on udpAddr and UDAP requests on
+// httpAddr, e.g. "127.0.0.1:1990" and "127.0.0.1:8080". Port 0 picks a
+// free port; see UDPAddr and HTTPAddr.
+func (e *WebOSEmulator) Listen(udpAddr, httpAddr string) error {
+	ua, err := net.ResolveUDPAddr(udp4, udpAddr)
+	if err != nil {
+		return err
+	}
+	if e.udp, err = net.ListenUDP(udp4, ua); err != nil {
+		return err
+	}
+
+	if e.http, err = net.Listen("tcp4", httpAddr); err != nil {
+		e.udp.Close()
+		return err
+	}
+
+	e.addr = e.http.Addr().(*net.TCPAddr)
+	e.mux = http.NewServeMux()
+	e.mux.HandleFunc(udapPair, e.pairing)
+	e.mux.HandleFunc(udapCommand, e.command)
+
+	go http.Serve(e.http, e.mux)
+	go e.discovery()
+
+	return nil
+}
+
+// UDPAddr returns the address answering discovery.
+func (e *WebOSEmulator) UDPAddr() *net.UDPAddr {
+	return e.udp.LocalAddr().(*net.UDPAddr)
+}
+
+// HTTPAddr returns the address serving the UDAP API.
+func (e *WebOSEmulator) HTTPAddr() *net.TCPAddr {
+	return e.addr
+}
+
+// Close stops the emulator.
+func (e *WebOSEmulator) Close() error {
+	e.udp.Close()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.standby {
+		return nil
+	}
+	return e.http.Close()
+}
+
+// Keys returns the HandleKeyInput codes received so far.
+func (e *WebOSEmulator) Keys() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]int(nil), e.keys...)
+}
+
+// Paired reports whether a client has paired since the last power off.
+func (e *WebOSEmulator) Paired() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.paired
+}
+
+// ShowingPIN reports whether a client asked the TV to display its PIN.
+func (e *WebOSEmulator) ShowingPIN() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.showing
+}
+
+// PowerOff simulates a power cycle, after which clients must pair again.
+func (e *WebOSEmulator) PowerOff() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.paired = false
+	e.showing = false
+}
+
+// Standby simulates the TV going into standby: it stops serving the UDAP
+// API, as a real set does, until a Wake-on-LAN packet for MAC arrives.
+func (e *WebOSEmulator) Standby() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.standby {
+		return
+	}
+	e.standby = true
+	e.paired = false
+	e.showing = false
+	e.http.Close()
+}
+
+// InStandby reports whether the emulator is waiting to be woken.
+func (e *WebOSEmulator) InStandby() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.standby
+}
+
+// wake brings the emulator out of standby on its old UDAP address.
+func (e *WebOSEmulator) wake() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.standby {
+		return
+	}
+	l, err := net.Listen("tcp4", e.addr.String())
+	if err != nil {
+		return
+	}
+	e.http, e.standby = l, false
+	go http.Serve(l, e.mux)
+}
+
+func (e *WebOSEmulator) discovery() {
+	var buf [1024]byte
+	for {
+		n, addr, err := e.udp.ReadFromUDP(buf[:])
+		if err != nil {
+			return
+		}
+		switch {
+		case bytes.HasPrefix(buf[:n], []byte("B-SEARCH")):
+			e.udp.WriteToUDP(e.announce(), addr)
+		case bytes.HasPrefix(buf[:n], []byte("M-SEARCH")):
+			e.udp.WriteToUDP(e.ssdpAnnounce(), addr)
+		case e.MAC != nil && bytes.HasPrefix(buf[:n], MagicPacket(e.MAC)):
+			e.wake()
+		}
+	}
+}
+
+// announce returns the reply to a B-SEARCH.
+func (e *WebOSEmulator) announce() []byte {
+	return []byte(`HTTP/1.1 200 OK` + cr +
+		`CACHE-CONTROL: max-age=1800` + cr +
+		fmt.Sprintf(`LOCATION: http://%v/`, e.HTTPAddr()) + cr +
+		`SERVER: Linux/2.6.18 UDAP/2.0 ` + e.Name + cr +
+		`ST: urn:schemas-udap:service:smartText:1` + cr + cr)
+}
+
+// ssdpAnnounce returns the reply to an M-SEARCH.
+func (e *WebOSEmulator) ssdpAnnounce() []byte {
+	return []byte(`HTTP/1.1 200 OK` + cr +
+		`CACHE-CONTROL: max-age=1800` + cr +
+		`EXT:` + cr +
+		fmt.Sprintf(`LOCATION: http://%v/description.xml`, e.HTTPAddr()) + cr +
+		`SERVER: Linux/2.6.18 UPnP/1.0 ` + e.Name + cr +
+		`ST: urn:schemas-upnp-org:device:MediaRenderer:1` + cr + cr)
+}
+
+func (e *WebOSEmulator) request(w http.ResponseWriter, r *http.Request) (udapEnvelope, bool) {
+	var env udapEnvelope
+
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return env, false
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = xml.Unmarshal(b, &env)
+	}
+	if err != nil {
+		http.Error(w, "malformed envelope", http.StatusBadRequest)
+		return env, false
+	}
+
+	return env, true
+}
+
+func (e *WebOSEmulator) pairing(w http.ResponseWriter, r *http.Request) {
+	env, ok := e.request(w, r)
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch env.API.Name {
+	case "showKey":
+		e.showing = true
+	case "hello":
+		if env.API.Value != e.Pin {
+			http.Error(w, "wrong PIN", http.StatusUnauthorized)
+			return
+		}
+		e.paired = true
+	case "byebye":
+		e.paired = false
+	default:
+		http.Error(w, "unknown pairing request", http.StatusBadRequest)
+		return
+	}
+
+	fmt.Fprint(w, "OK")
+}
+
+func (e *WebOSEmulator) command(w http.ResponseWriter, r *http.Request) {
+	env, ok := e.request(w, r)
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.paired {
+		http.Error(w, "not paired", http.StatusUnauthorized)
+		return
+	}
+
+	key, err := strconv.Atoi(env.API.Value)
+	if env.API.Name != "HandleKeyInput" || err != nil {
+		http.Error(w, "unknown command", http.StatusBadRequest)
+		return
+	}
+
+	e.keys = append(e.keys, key)
+	fmt.Fprint(w, "OK")
+}
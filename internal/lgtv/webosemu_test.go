@@ -0,0 +1,226 @@
+package lgtv
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	logging "github.com/op/go-logging"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestWebOS(t *testing.T, pin string) (*WebOSEmulator, *WebOS) {
+	e := NewWebOSEmulator("42LW5700", "123456")
+	if err := e.Listen("127.0.0.1:0", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	return e, &WebOS{
+		Logger: logging.MustGetLogger("test"),
+		IP:     net.IPv4(127, 0, 0, 1),
+		Name:   e.Name,
+		Pin:    pin,
+		Port:   e.HTTPAddr().Port,
+	}
+}
+
+func TestWebOSEmulatorDiscovery(t *testing.T) {
+	e, _ := newTestWebOS(t, "")
+	defer e.Close()
+
+	Convey("Testing WebOSEmulator discovery", t, func() {
+		c, err := net.DialUDP(udp4, nil, e.UDPAddr())
+		So(err, ShouldBeNil)
+		defer c.Close()
+
+		_, err = c.Write([]byte("B-SEARCH * HTTP/1.1" + cr + cr))
+		So(err, ShouldBeNil)
+
+		var buf [1024]byte
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := c.Read(buf[:])
+		So(err, ShouldBeNil)
+		So(string(buf[:n]), ShouldContainSubstring, "SERVER: Linux/2.6.18 UDAP/2.0 42LW5700")
+
+		w := &WebOS{Logger: logging.MustGetLogger("test"), Port: e.HTTPAddr().Port}
+		ok, err := w.parseMsg(string(buf[:n]), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1990})
+		So(ok, ShouldBeTrue)
+		So(w.Name, ShouldEqual, "42LW5700")
+		So(e.ShowingPIN(), ShouldBeTrue)
+
+		// A reply on the UDAP port that doesn't name its server is skipped
+		ok, err = w.parseMsg("HTTP/1.1 200 OK"+cr+cr, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 1990})
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+		So(w.IP.String(), ShouldEqual, "127.0.0.1")
+	})
+}
+
+func TestWebOSEmulatorPairing(t *testing.T) {
+	Convey("Testing WebOS against a WebOSEmulator", t, func() {
+		Convey("running test: Wrong PIN", func() {
+			e, w := newTestWebOS(t, "000000")
+			defer e.Close()
+
+			So(w.pairingRequest(), ShouldBeNil)
+			So(e.ShowingPIN(), ShouldBeTrue)
+
+			So(errors.Is(w.Pair(), errRefused), ShouldBeTrue)
+			So(e.Paired(), ShouldBeFalse)
+			So(w.Zap(Cmd["Home"].Web), ShouldBeFalse)
+			So(e.Keys(), ShouldBeEmpty)
+		})
+
+		Convey("running test: Pair and zap", func() {
+			e, w := newTestWebOS(t, "123456")
+			defer e.Close()
+
+			So(w.Pair(), ShouldBeNil)
+			So(e.Paired(), ShouldBeTrue)
+			So(w.Zap(Cmd["Home"].Web), ShouldBeTrue)
+			So(w.Do(context.Background(), "MuteOn"), ShouldBeNil)
+			So(e.Keys(), ShouldResemble, []int{21, 26})
+		})
+
+		Convey("running test: Re-pair after power off", func() {
+			e, w := newTestWebOS(t, "123456")
+			defer e.Close()
+
+			So(w.Zap(Cmd["Home"].Web), ShouldBeTrue)
+			e.PowerOff()
+			So(e.Paired(), ShouldBeFalse)
+			So(w.Zap(Cmd["Back"].Web), ShouldBeTrue)
+			So(e.Keys(), ShouldResemble, []int{21, 23})
+		})
+
+		Convey("running test: Send rejects garbage", func() {
+			e, w := newTestWebOS(t, "123456")
+			defer e.Close()
+
+			code, _, err := w.Send(udapCommand, []byte("not xml"))
+			So(err, ShouldBeNil)
+			So(code, ShouldEqual, 400)
+		})
+
+		Convey("running test: Remote fails over to WebOS", func() {
+			e, w := newTestWebOS(t, "123456")
+			defer e.Close()
+
+			r := &Remote{
+				Serial: &Serial{
+					ID:          1,
+					ReadTimeout: time.Second,
+					conn:        &fakePort{replies: map[string]string{"ke 01 00\r": "e 01 NG00x"}},
+				},
+				WebOS:    w,
+				Failover: true,
+			}
+			res := r.Send(context.Background(), "MuteOn")
+			So(res.Err, ShouldBeNil)
+			So(res.Via, ShouldEqual, WebOSTransport)
+			So(e.Keys(), ShouldResemble, []int{26})
+		})
+
+		Convey("running test: Independent clients in one process", func() {
+			e1, w1 := newTestWebOS(t, "123456")
+			defer e1.Close()
+			defer w1.Close()
+			e2 := NewWebOSEmulator("55LM8600", "654321")
+			So(e2.Listen("127.0.0.1:0", "127.0.0.1:0"), ShouldBeNil)
+			defer e2.Close()
+			w2 := &WebOS{Logger: w1.Logger, IP: w1.IP, Pin: "654321", Port: e2.HTTPAddr().Port}
+			defer w2.Close()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					w1.Do(context.Background(), "Home")
+				}()
+				go func() {
+					defer wg.Done()
+					w2.Do(context.Background(), "Back")
+				}()
+			}
+			wg.Wait()
+
+			So(e1.Keys(), ShouldResemble, []int{21, 21, 21, 21, 21, 21, 21, 21, 21, 21})
+			So(e2.Keys(), ShouldResemble, []int{23, 23, 23, 23, 23, 23, 23, 23, 23, 23})
+			So(w1.Close(), ShouldBeNil)
+		})
+	})
+}
+
+// hungTV accepts connections on a UDAP port and never answers, like a set
+// whose web server has wedged. It returns the port and a count of the
+// connections accepted so far.
+func hungTV(t *testing.T) (int, func() int) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	t.Cleanup(func() {
+		l.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range conns {
+			c.Close()
+		}
+	})
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, c)
+			mu.Unlock()
+			go io.Copy(ioutil.Discard, c)
+		}
+	}()
+
+	accepted := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(conns)
+	}
+	return l.Addr().(*net.TCPAddr).Port, accepted
+}
+
+func TestWebOSHungTV(t *testing.T) {
+	Convey("Testing WebOS against a TV that never answers", t, func() {
+		port, _ := hungTV(t)
+		w := &WebOS{Logger: logging.MustGetLogger("test"), IP: net.IPv4(127, 0, 0, 1), Port: port, Timeout: 10 * time.Second}
+		defer w.Close()
+
+		Convey("running test: Do gives up when ctx does", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			So(w.Do(ctx, "Home"), ShouldNotBeNil)
+			So(time.Since(start), ShouldBeLessThan, 2*time.Second)
+		})
+
+		Convey("running test: Close isn't held up by a request", func() {
+			go w.Do(context.Background(), "Home")
+			time.Sleep(100 * time.Millisecond)
+
+			start := time.Now()
+			So(w.Close(), ShouldBeNil)
+			So(time.Since(start), ShouldBeLessThan, 100*time.Millisecond)
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package lgtv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// arpTable is the kernel's IPv4 neighbour table
+	arpTable = "/proc/net/arp"
+
+	// wolPort is where magic packets are sent; 9 is discard
+	wolPort = 9
+
+	// wakePoll paces checks for a woken TV, and wakeTimeout bounds them;
+	// sets take a while to boot their network stack.
+	wakePoll    = time.Second
+	wakeTimeout = 60 * time.Second
+)
+
+// MagicPacket returns the Wake-on-LAN packet for mac: six 0xFF bytes and
+// mac sixteen times over.
+func MagicPacket(mac net.HardwareAddr) []byte {
+	b := bytes.Repeat([]byte{0xFF}, 6)
+	for i := 0; i < 16; i++ {
+		b = append(b, mac...)
+	}
+	return b
+}
+
+// Wake broadcasts a Wake-on-LAN magic packet for mac.
+func Wake(mac net.HardwareAddr) error {
+	return wake(mac, &net.UDPAddr{}, net.IPv4bcast)
+}
+
+// wake sends mac's magic packet from laddr to broadcast address bcast.
+func wake(mac net.HardwareAddr, laddr *net.UDPAddr, bcast net.IP) error {
+	if len(mac) != 6 {
+		return fmt.Errorf("bad MAC address %q", mac)
+	}
+
+	conn, err := net.ListenUDP(udp4, laddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.WriteToUDP(MagicPacket(mac), &net.UDPAddr{IP: bcast, Port: wolPort})
+	return err
+}
+
+// lookupMAC returns the MAC address the ARP table holds for ip, which it
+// will for a TV that has just answered discovery.
+func lookupMAC(ip net.IP) (net.HardwareAddr, error) {
+	f, err := os.Open(arpTable)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// IP address  HW type  Flags  HW address  Mask  Device
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fs := strings.Fields(sc.Text())
+		if len(fs) < 4 || !ip.Equal(net.ParseIP(fs[0])) {
+			continue
+		}
+
+		// Flags of zero mark an entry still waiting for an answer
+		if flags, err := strconv.ParseUint(fs[2], 0, 16); err != nil || flags == 0 {
+			continue
+		}
+		if mac, err := net.ParseMAC(fs[3]); err == nil {
+			return mac, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no ARP entry for %v", ip)
+}
+
+// PowerOn wakes the TV with a Wake-on-LAN packet and waits until it serves
+// UDAP again. A TV in standby doesn't listen on the network, so the POWER
+// key code can't reach it, and would switch off one that's already on.
+// The packet goes to MAC, or the address the ARP table holds for IP, and
+// is broadcast on Interface's subnet if it's set.
+func (w *WebOS) PowerOn(ctx context.Context) error {
+	w.mu.Lock()
+	ip, mac := w.IP, w.MAC
+	w.mu.Unlock()
+
+	if w.answering(ip) {
+		return nil
+	}
+
+	if mac == nil {
+		var err error
+		if mac, err = lookupMAC(ip); err != nil {
+			return fmt.Errorf("no MAC address to wake %v: %v", ip, err)
+		}
+	}
+
+	laddr, bcast := &net.UDPAddr{}, net.IPv4bcast
+	if w.Interface != "" {
+		a, err := w.iface()
+		if err != nil {
+			return err
+		}
+		laddr, bcast = a.udpAddr(), a.broadcast()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wakeTimeout)
+	defer cancel()
+
+	t := time.NewTicker(wakePoll)
+	defer t.Stop()
+	for {
+		// Resend in case a packet went astray while the NIC was dozing
+		if err := wake(mac, laddr, bcast); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("%w: %v didn't wake within %v", ErrTimeout, ip, wakeTimeout)
+			}
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		if w.answering(ip) {
+			return nil
+		}
+	}
+}
+
+// answering reports whether the TV's UDAP port at ip takes connections.
+func (w *WebOS) answering(ip net.IP) bool {
+	c, err := net.DialTimeout("tcp", net.JoinHostPort(ip.String(), strconv.Itoa(w.port())), wakePoll)
+	if err != nil {
+		return false
+	}
+	c.Close()
+	return true
+}
@@ -0,0 +1,164 @@
+package lgtv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	logging "github.com/op/go-logging"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMagicPacket(t *testing.T) {
+	Convey("Testing MagicPacket()", t, func() {
+		mac := net.HardwareAddr{0xa8, 0x23, 0xfe, 0x01, 0x02, 0x03}
+		b := MagicPacket(mac)
+
+		So(b, ShouldHaveLength, 102)
+		So(b[:6], ShouldResemble, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+		So(bytes.Count(b, mac), ShouldEqual, 16)
+		So(Wake(mac[:4]).Error(), ShouldEqual, `bad MAC address "a8:23:fe:01"`)
+	})
+}
+
+func TestLookupMAC(t *testing.T) {
+	table := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+192.168.1.20     0x1         0x2         a8:23:fe:01:02:03     *        eth0
+192.168.1.21     0x1         0x0         00:00:00:00:00:00     *        eth0
+`
+	path := filepath.Join(t.TempDir(), "arp")
+	if err := ioutil.WriteFile(path, []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := arpTable
+	arpTable = path
+	defer func() { arpTable = old }()
+
+	Convey("Testing lookupMAC()", t, func() {
+		tests := []struct {
+			name   string
+			ip     net.IP
+			want   string
+			errMsg string
+		}{
+			{name: "Known", ip: net.IPv4(192, 168, 1, 20), want: "a8:23:fe:01:02:03"},
+			{name: "Incomplete", ip: net.IPv4(192, 168, 1, 21), errMsg: "no ARP entry for 192.168.1.21"},
+			{name: "Unknown", ip: net.IPv4(192, 168, 1, 99), errMsg: "no ARP entry for 192.168.1.99"},
+		}
+
+		for _, tt := range tests {
+			Convey("running test: "+tt.name, func() {
+				mac, err := lookupMAC(tt.ip)
+				if tt.errMsg != "" {
+					So(err.Error(), ShouldEqual, tt.errMsg)
+					return
+				}
+				So(err, ShouldBeNil)
+				So(mac.String(), ShouldEqual, tt.want)
+			})
+		}
+	})
+}
+
+func TestWebOSPowerOn(t *testing.T) {
+	mac := net.HardwareAddr{0xa8, 0x23, 0xfe, 0x01, 0x02, 0x03}
+
+	oldPort, oldPoll, oldTimeout, oldTable := wolPort, wakePoll, wakeTimeout, arpTable
+	wakePoll, wakeTimeout = 20*time.Millisecond, 300*time.Millisecond
+	defer func() { wolPort, wakePoll, wakeTimeout, arpTable = oldPort, oldPoll, oldTimeout, oldTable }()
+
+	// On a /32 the subnet broadcast is the address itself, so packets
+	// broadcast on tv0 reach the emulator on loopback
+	oldIfs, oldAddrs := interfaces, ifaceAddrs
+	interfaces = func() ([]net.Interface, error) {
+		return []net.Interface{{Index: 1, Name: "tv0", Flags: net.FlagUp | net.FlagBroadcast}}, nil
+	}
+	ifaceAddrs = func(*net.Interface) ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(32, 32)}}, nil
+	}
+	defer func() { interfaces, ifaceAddrs = oldIfs, oldAddrs }()
+
+	Convey("Testing WebOS PowerOn", t, func() {
+		// MAC must be set before Listen starts answering the discovery port
+		e := &WebOSEmulator{Name: "42LW5700", Pin: "123456", MAC: mac}
+		if err := e.Listen("127.0.0.1:0", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		defer e.Close()
+		w := &WebOS{Logger: logging.MustGetLogger("test"), Interface: "tv0", IP: net.IPv4(127, 0, 0, 1), Pin: "123456", Port: e.HTTPAddr().Port}
+		wolPort = e.UDPAddr().Port
+		arpTable = filepath.Join(t.TempDir(), "arp")
+
+		Convey("running test: Wakes a TV in standby", func() {
+			e.Standby()
+			w.MAC = mac
+
+			So(w.Do(context.Background(), "PowerOn"), ShouldBeNil)
+			So(e.InStandby(), ShouldBeFalse)
+
+			// Keys still reach the woken set, after pairing again
+			So(w.Do(context.Background(), "VolUp"), ShouldBeNil)
+			So(e.Keys(), ShouldResemble, []int{24})
+		})
+
+		Convey("running test: Discovery moves the TV while it wakes", func() {
+			e.Standby()
+			w.MAC = mac
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				w.mu.Lock()
+				w.IP = net.IPv4(127, 0, 0, 1)
+				w.mu.Unlock()
+			}()
+			So(w.PowerOn(context.Background()), ShouldBeNil)
+			<-done
+			So(e.InStandby(), ShouldBeFalse)
+		})
+
+		Convey("running test: No address on Interface", func() {
+			e.Standby()
+			w.MAC, w.Interface = mac, "eth9"
+
+			err := w.PowerOn(context.Background())
+			So(err.Error(), ShouldEqual, `no usable address on interface "eth9"`)
+		})
+
+		Convey("running test: Leaves a TV that's on alone", func() {
+			So(w.Do(context.Background(), "PowerOn"), ShouldBeNil)
+			So(e.Keys(), ShouldBeEmpty)
+		})
+
+		Convey("running test: MAC from the ARP table", func() {
+			So(ioutil.WriteFile(arpTable, []byte("IP address HW type Flags HW address Mask Device\n"+
+				"127.0.0.1 0x1 0x2 a8:23:fe:01:02:03 * lo\n"), 0644), ShouldBeNil)
+			e.Standby()
+
+			So(w.PowerOn(context.Background()), ShouldBeNil)
+			So(e.InStandby(), ShouldBeFalse)
+		})
+
+		Convey("running test: No MAC to wake", func() {
+			e.Standby()
+
+			err := w.PowerOn(context.Background())
+			So(err.Error(), ShouldStartWith, "no MAC address to wake 127.0.0.1: ")
+		})
+
+		Convey("running test: Wrong MAC never wakes", func() {
+			e.Standby()
+			w.MAC = net.HardwareAddr{0, 1, 2, 3, 4, 5}
+
+			err := w.PowerOn(context.Background())
+			So(errors.Is(err, ErrTimeout), ShouldBeTrue)
+			So(err.Error(), ShouldEndWith, "127.0.0.1 didn't wake within 300ms")
+		})
+	})
+}
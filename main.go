@@ -1,16 +1,154 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/britannic/lgtv-remote/internal/lgtv"
+	logging "github.com/op/go-logging"
 	"github.com/tarm/serial"
 )
 
+const usage = `Usage: %s [flags] [command]
+
+Commands:
+  emulate	emulate -sets TV sets on a pseudo-terminal until interrupted
+  list-ports	list serial ports with the identities -port can select them by
+  discover	list the networked TVs that answer UDAP or SSDP within -wait,
+		searching from -iface if it's set, and record them in -registry
+  tvs		list the TV sets in -registry, which -tv picks one of by name
+  register NAME	record -port, -id or both for TV set NAME in -registry, keeping
+		what discovery and pairing learnt about it
+  rename OLD NEW	rename a TV set in -registry
+  pair NAME PIN	pair with networked TV set NAME in -registry using the PIN it
+		shows, and record the PIN there
+  do NAME	send the named command, e.g. MuteOn, to TV set -id
+  monitor [PORT2]	decode traffic between a controller and TV sets on -port, and
+		PORT2 if the line's two directions are on separate adapters
+  scan		probe set IDs 1 to -max and report which TV sets answer
+  serve [ADDR]	share the serial port with LG protocol clients on TCP ADDR (default :4001)
+  set NAME N	set ranged command NAME, e.g. VolSet, to N on TV set -id
+  state		print a JSON snapshot of every readable setting of TV set -id
+
+Flags:
+`
+
+// emulate serves emulated TV sets 1 to n on a pseudo-terminal until SIGINT
+func emulate(n int) {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+
+	path, err := lgtv.NewSerialEmulator(ids...).ListenPTY()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Emulating %d TV set(s) on %s", n, path)
+	select {}
+}
+
+// monitor prints the LG protocol traffic on ports until interrupted
+func monitor(baud int, ports []string, json bool) {
+	var taps []io.Reader
+	for _, p := range ports {
+		s := &lgtv.Serial{Baud: baud, Parity: serial.ParityNone, Port: p, ReadTimeout: 100 * time.Millisecond}
+		t, err := s.Tap()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer t.Close()
+		taps = append(taps, t)
+	}
+
+	m := &lgtv.Monitor{Out: os.Stdout, JSON: json}
+	if err := m.Watch(context.Background(), taps...); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// webOS returns a client for networked TV set dev
+func webOS(dev lgtv.Device, tries int) *lgtv.WebOS {
+	if dev.IP == nil {
+		log.Fatalf("%s has no IP address", dev.Name)
+	}
+
+	var mac net.HardwareAddr
+	if dev.MAC != "" {
+		var err error
+		if mac, err = net.ParseMAC(dev.MAC); err != nil {
+			log.Fatalf("%s: %v", dev.Name, err)
+		}
+	}
+
+	return &lgtv.WebOS{
+		Logger: logging.MustGetLogger("lgtv"),
+		MAC:    mac,
+		IP:     dev.IP,
+		Name:   dev.Name,
+		Pin:    dev.Pin,
+		Retry:  lgtv.RetryPolicy{Attempts: tries, Backoff: 250 * time.Millisecond},
+	}
+}
+
+// web runs the command on networked TV set dev, which has no serial port
+func web(dev lgtv.Device, tries int) {
+	if dev.IP == nil {
+		log.Fatalf("%s has neither a serial port nor an IP address", dev.Name)
+	}
+
+	w := webOS(dev, tries)
+	defer w.Close()
+
+	switch cmd := flag.Arg(0); cmd {
+	case "do":
+		if err := w.Do(context.Background(), flag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("%s: %q needs a serial port", dev.Name, cmd)
+	}
+}
+
+// pair pairs with networked TV set name in reg using pin, and records the
+// PIN there once the TV accepts it
+func pair(reg *lgtv.Registry, name, pin string, tries int) {
+	dev, err := reg.Lookup(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dev.Pin = pin
+	w := webOS(dev, tries)
+	defer w.Close()
+
+	if err := w.Pair(); err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+
+	err = reg.Update(func(m map[string]*lgtv.Device) error {
+		d, ok := m[name]
+		if !ok {
+			return fmt.Errorf("%q left %s while pairing", name, reg.Path)
+		}
+		d.Pin = pin
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
 // trap SIGINT and exit if received
 func sigExit(i int) {
 	sig := make(chan os.Signal, 1)
@@ -23,20 +161,185 @@ func sigExit(i int) {
 
 func main() {
 	sigExit(1)
-	port := flag.String("port", "/dev/ttys000", "set serial device")
+	baud := flag.Int("baud", 9600, "set serial baud rate, 0 to detect baud rate and parity")
+	id := flag.Int("id", 1, "set TV set ID, 0 to broadcast to every set")
+	max := flag.Int("max", lgtv.MaxTVs, "set how many TV sets are on the chain, which have IDs 1 to -max")
+	port := flag.String("port", "/dev/ttys000", "set serial device, select one by serial:SERIAL, usb:VID:PID or a glob, or reach one at tcp://HOST:PORT or rfc2217://HOST:PORT")
+	gap := flag.Duration("gap", 0, "set the pause between serial commands")
+	tries := flag.Int("tries", 1, "set how many times to send a command that times out")
+	sets := flag.Int("sets", 1, "set how many TV sets to emulate")
+	iface := flag.String("iface", "", "set the interfaces discover searches from, by name or CIDR, comma separated")
+	wait := flag.Duration("wait", 3*time.Second, "set how long discover waits for TVs to answer")
+	jsonOut := flag.Bool("json", false, "print monitored frames as JSON lines")
+	regPath := flag.String("registry", lgtv.DefaultRegistryPath(), "set the file of known TV sets")
+	tv := flag.String("tv", "", "set the TV set by its name in -registry instead of -port and -id")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	reg := &lgtv.Registry{Path: *regPath}
+
+	switch flag.Arg(0) {
+	case "emulate":
+		emulate(*sets)
+	case "discover":
+		var sel []string
+		if *iface != "" {
+			sel = strings.Split(*iface, ",")
+		}
+		tvs, err := lgtv.DiscoverOn(context.Background(), *wait, sel...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := reg.Record(tvs, time.Now()); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(tvs)
+		return
+	case "tvs":
+		devs, err := reg.Devices()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(devs)
+		return
+	case "register":
+		if flag.Arg(1) == "" {
+			log.Fatal("register needs a NAME")
+		}
+		// Only the flags given change the entry, so a networked set
+		// doesn't pick up the default -port
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["port"] && !set["id"] {
+			log.Fatal("register needs -port or -id")
+		}
+
+		// Keep what discovery and pairing learnt about a known set
+		name := flag.Arg(1)
+		err := reg.Update(func(m map[string]*lgtv.Device) error {
+			d, ok := m[name]
+			if !ok {
+				d = &lgtv.Device{Name: name}
+				m[name] = d
+			}
+			if set["port"] {
+				d.Port = *port
+			}
+			// A new set gets the default -id rather than broadcast
+			if set["id"] || !ok {
+				d.SetID = *id
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "pair":
+		if flag.Arg(1) == "" || flag.Arg(2) == "" {
+			log.Fatal("pair needs a NAME and a PIN")
+		}
+		pair(reg, flag.Arg(1), flag.Arg(2), *tries)
+		return
+	case "rename":
+		if err := reg.Rename(flag.Arg(1), flag.Arg(2)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "list-ports":
+		ports, err := lgtv.ListPorts()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(ports)
+		return
+	case "monitor":
+		if *baud == 0 {
+			log.Fatal("monitor needs the line's -baud rate")
+		}
+		monitor(*baud, append([]string{*port}, flag.Args()[1:]...), *jsonOut)
+		return
+	}
+
+	if *tv != "" {
+		dev, err := reg.Lookup(*tv)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if dev.Port == "" {
+			web(dev, *tries)
+			return
+		}
+		*port, *id = dev.Port, dev.SetID
+	}
+
 	s := lgtv.Serial{
-		Baud:        9600,
-		Cmd:         lgtv.Cmd.SetSerialCmds(),
+		Baud:        *baud,
+		Gap:         *gap,
+		ID:          *id,
+		MaxID:       *max,
 		Parity:      serial.ParityNone,
 		Port:        *port,
 		ReadTimeout: 1 * time.Second,
+		Retry:       lgtv.RetryPolicy{Attempts: *tries, Backoff: 250 * time.Millisecond},
+
+		// A gateway outlives unplugging the adapter
+		Reconnect: flag.Arg(0) == "serve",
 	}
 
-	tty, err := s.Open()
-	if err != nil {
+	ctx := context.Background()
+
+	if *baud == 0 {
+		if err := s.Detect(ctx); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Detected %d baud, parity %c", s.Baud, s.Parity)
+	} else if err := s.Open(); err != nil {
 		log.Fatal(err)
 	}
-	defer tty.Close()
+	defer s.Close()
+
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+	case "do":
+		if err := s.Do(ctx, flag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+	case "scan":
+		rep, err := s.Scan(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(rep)
+	case "serve":
+		addr := flag.Arg(1)
+		if addr == "" {
+			addr = ":4001"
+		}
+		log.Printf("Serving %s on %s", *port, addr)
+		g := &lgtv.Gateway{Serial: &s}
+		if err := g.ListenAndServe(addr); err != nil {
+			log.Fatal(err)
+		}
+	case "set":
+		n, err := strconv.Atoi(flag.Arg(2))
+		if err != nil {
+			log.Fatalf("set %s: %v", flag.Arg(1), err)
+		}
+		if err := s.Set(ctx, *id, flag.Arg(1), n); err != nil {
+			log.Fatal(err)
+		}
+	case "state":
+		st, err := s.State(ctx, *id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(st)
+	default:
+		flag.Usage()
+		log.Fatalf("unknown command: %q", cmd)
+	}
 }